@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resumeSheet continues the existing sheet (the last continuation sheet with --split) beneath its rows for --append.
+// The header of the sheet is taken as it is written, to be checked against those of CSVs.
+func (c globalCmd) resumeSheet(oc outputContext, st *sheetState) error {
+	if c.Split {
+		for part := 2; ; part++ {
+			name := splitSheetName(st.sheet, part)
+			if idx, _ := oc.output.GetSheetIndex(name); idx == -1 {
+				break
+			}
+			st.part = part
+			st.xlsxsheet = name
+			st.row0, st.col0 = 0, 0
+		}
+	}
+
+	rows, err := oc.output.GetRows(st.xlsxsheet)
+	if err != nil {
+		return fmt.Errorf("%v: %v", st.xlsxsheet, err)
+	}
+
+	// rows from the origin, up to the last one with values
+	var written [][]string
+	for rindex := st.row0; rindex < len(rows); rindex++ {
+		var row []string
+		if len(rows[rindex]) > st.col0 {
+			row = rows[rindex][st.col0:]
+		}
+		written = append(written, row)
+	}
+	for len(written) > 0 && strings.Join(written[len(written)-1], "") == "" {
+		written = written[:len(written)-1]
+	}
+	if len(written) == 0 {
+		return nil
+	}
+
+	first := 0
+	if c.Header > 0 {
+		st.header = written[0]
+		st.names = written[0]
+		if c.SourceColumn != "" && len(st.names) > 0 {
+			st.names = st.names[1:]
+		}
+		if c.Autofit {
+			st.widths.addHeader(st.header, c.Table || st.layout.autofilter)
+		}
+		first = 1
+	}
+	if c.Autofit {
+		for _, row := range written[first:] {
+			for cindex, value := range row {
+				st.widths.add(cindex, textWidth(value))
+			}
+		}
+	}
+
+	st.rindex = len(written)
+
+	return nil
+}
+
+// headerMatches reports whether the header of a CSV is that of the sheet, as written by writeHeader.
+func (c globalCmd) headerMatches(st *sheetState, columns []string) bool {
+	if c.SourceColumn != "" {
+		columns = append([]string{c.SourceColumn}, columns...)
+	}
+	if c.Table {
+		columns = tableHeader(columns)
+	}
+
+	return sameNames(st.header, columns)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/width"
+)
+
+const (
+	// autofitPadding is added to the widest value of a column.
+	autofitPadding = 2
+	// filterButtonWidth is added to a header with a filter button.
+	filterButtonWidth = 2
+)
+
+// columnWidths are the widths of the widest rendered values of columns.
+type columnWidths struct {
+	chars []int
+
+	// rawChars are the longest unformatted values of columns by styles,
+	// so that only values possibly wider are rendered.
+	rawChars []map[int]int
+}
+
+func (cw *columnWidths) add(cindex, w int) {
+	for len(cw.chars) <= cindex {
+		cw.chars = append(cw.chars, 0)
+		cw.rawChars = append(cw.rawChars, make(map[int]int))
+	}
+	if w > cw.chars[cindex] {
+		cw.chars[cindex] = w
+	}
+}
+
+// addRow measures cells written in the row rindex.
+// Strings are measured as they are, and others are measured as rendered with their number formats.
+func (cw *columnWidths) addRow(w *sheetWriter, rindex int, cells []interface{}) error {
+	for cindex, cell := range cells {
+		if cell == nil {
+			continue
+		}
+
+		c := cell.(excelize.Cell)
+		if c.Formula != "" {
+			// the result is unknown until calculated
+			continue
+		}
+
+		s, ok := c.Value.(string)
+		if !ok || c.StyleID != 0 {
+			raw := len(fmt.Sprint(c.Value))
+			if t, ok := c.Value.(time.Time); ok {
+				// for month names
+				raw += len(t.Month().String())
+			}
+			if cindex < len(cw.rawChars) && raw <= cw.rawChars[cindex][c.StyleID] {
+				continue
+			}
+			cw.add(cindex, 0)
+			cw.rawChars[cindex][c.StyleID] = raw
+
+			addr, err := w.cellName(cindex, rindex)
+			if err != nil {
+				return err
+			}
+			s, err = w.f.GetCellValue(w.sheet, addr)
+			if err != nil {
+				return err
+			}
+		}
+
+		cw.add(cindex, textWidth(s))
+	}
+
+	return nil
+}
+
+// apply sets widths of columns within min and max.
+func (cw columnWidths) apply(w *sheetWriter, min, max float64) error {
+	for cindex, chars := range cw.chars {
+		if chars == 0 {
+			continue
+		}
+
+		colWidth := float64(chars + autofitPadding)
+		if colWidth < min {
+			colWidth = min
+		}
+		if colWidth > max {
+			colWidth = max
+		}
+
+		err := w.setColWidth(cindex, colWidth)
+		if err != nil {
+			return fmt.Errorf("%v: %v", w.sheet, err)
+		}
+	}
+
+	return nil
+}
+
+// textWidth is the width of the longest line, where East Asian wide characters are 2.
+func textWidth(s string) int {
+	longest := 0
+	for _, line := range strings.Split(s, "\n") {
+		n := 0
+		for _, r := range line {
+			switch width.LookupRune(r).Kind() {
+			case width.EastAsianWide, width.EastAsianFullwidth:
+				n += 2
+			default:
+				n++
+			}
+		}
+		if n > longest {
+			longest = n
+		}
+	}
+
+	return longest
+}
+
+// addHeader measures the header, with room for filter buttons of an autofilter or a table.
+func (cw *columnWidths) addHeader(header []string, button bool) {
+	for cindex, name := range header {
+		w := textWidth(name)
+		if button {
+			w += filterButtonWidth
+		}
+		cw.add(cindex, w)
+	}
+}
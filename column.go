@@ -1,30 +1,136 @@
 package main
 
 import (
+	"fmt"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/shu-go/gli/v2"
 	"github.com/xuri/excelize/v2"
 )
 
+// columnDecl is a declaration of --columns.
+type columnDecl struct {
+	Key  string // [SHEET!]COLUMN_NAME, or the whole declaration if invalid
+	Type string // empty if invalid
+}
+
+// columnDecls keeps --columns in the order of declaration.
+type columnDecls []columnDecl
+
+func init() {
+	gli.RegisterTypeDecoder(reflect.TypeOf(columnDecls{}), columnDeclsDecoder)
+}
+
+var (
+	unescapedCommaRE = regexp.MustCompile(`(?:\\,|[^,])+`)
+	declTypeRE       = regexp.MustCompile(`\s*:\s*((?:` + typeNamesPattern + `)(?:\(.*\))?)$`)
+)
+
+// columnDeclsDecoder decodes KEY:TYPE,KEY:TYPE,... (commas in formats are escaped as \,).
+// A redeclared key replaces the former declaration in its place.
+// The type is looked for at the end, so that a key may have colons, like re:^a:b$:text.
+// Invalid declarations are kept to be reported by parseHints, as gli replaces errors of decoders with its own.
+func columnDeclsDecoder(s string, v reflect.Value, tag reflect.StructTag, firstTime bool) error {
+	if firstTime {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+
+	decls := v.Interface().(columnDecls)
+	for _, elem := range unescapedCommaRE.FindAllString(s, -1) {
+		elem = strings.TrimSpace(elem)
+		elem = strings.ReplaceAll(elem, `\,`, `,`)
+
+		loc := declTypeRE.FindStringSubmatchIndex(elem)
+		if loc == nil || loc[0] == 0 {
+			decls = append(decls, columnDecl{Key: elem})
+			continue
+		}
+
+		decl := columnDecl{
+			Key:  elem[:loc[0]],
+			Type: elem[loc[2]:loc[3]],
+		}
+
+		// a declaration of the same key is overridden, as the last one wins
+		found := false
+		for i := range decls {
+			if strings.EqualFold(decls[i].Key, decl.Key) {
+				decls[i] = decl
+				found = true
+			}
+		}
+		if !found {
+			decls = append(decls, decl)
+		}
+	}
+	v.Set(reflect.ValueOf(decls))
+
+	return nil
+}
+
 type columns []column
 
 type column struct {
 	Sheet string
 	Name  string
 
+	// patterns by re:
+	sheetRE *regexp.Regexp
+	nameRE  *regexp.Regexp
+
+	// Rule is the declaration for --explain-columns.
+	Rule string
+
 	Type derivedType
 }
 
-func newColumn(s string, typ derivedType) column {
-	s = strings.ToLower(s)
-	c := column{Sheet: "", Name: s, Type: typ}
+// regexpPrefix marks a sheet or a column name as a regular expression.
+const regexpPrefix = "re:"
+
+// newColumn makes a hint of [SHEET!]COLUMN_NAME.
+func newColumn(s string, typ derivedType) (column, error) {
+	sheet, name := "", s
 	if pos := strings.Index(s, "!"); pos != -1 {
-		c.Sheet = s[:pos]
-		c.Name = s[pos+1:]
+		sheet, name = s[:pos], s[pos+1:]
+	}
+	return newSheetColumn(sheet, name, typ)
+}
+
+// newSheetColumn makes a hint of the sheet and the column name.
+// Names are wildcards, or regular expressions (case-insensitive) prefixed by re:.
+func newSheetColumn(sheet, name string, typ derivedType) (column, error) {
+	c := column{Rule: name, Type: typ}
+	if sheet != "" {
+		c.Rule = sheet + "!" + name
+	}
+
+	var err error
+	c.Sheet, c.sheetRE, err = compileColumnPattern(sheet)
+	if err != nil {
+		return column{}, err
+	}
+	c.Name, c.nameRE, err = compileColumnPattern(name)
+	if err != nil {
+		return column{}, err
+	}
+
+	return c, nil
+}
+
+func compileColumnPattern(s string) (string, *regexp.Regexp, error) {
+	if !strings.HasPrefix(s, regexpPrefix) {
+		return strings.ToLower(s), nil, nil
 	}
-	return c
+
+	re, err := regexp.Compile("(?i)" + strings.TrimPrefix(s, regexpPrefix))
+	if err != nil {
+		return "", nil, fmt.Errorf("%v: %v", s, err)
+	}
+	return s, re, nil
 }
 
 func (cc columns) findByName(sheet, name string, optColNum ...int) int {
@@ -68,17 +174,17 @@ func (cc columns) findByName(sheet, name string, optColNum ...int) int {
 
 	// name:exact, sheet:wildcard
 	for i, c := range cc {
-		if strings.EqualFold(c.Name, name) && wildcardMatch(c.Sheet, sheet) {
+		if strings.EqualFold(c.Name, name) && c.sheetMatch(sheet) {
 			return i
 		}
 	}
 	for i, c := range cc {
-		if strings.EqualFold(c.Name, colNameAlpha) && wildcardMatch(c.Sheet, sheet) {
+		if strings.EqualFold(c.Name, colNameAlpha) && c.sheetMatch(sheet) {
 			return i
 		}
 	}
 	for i, c := range cc {
-		if strings.EqualFold(c.Name, colNameNum) && wildcardMatch(c.Sheet, sheet) {
+		if strings.EqualFold(c.Name, colNameNum) && c.sheetMatch(sheet) {
 			return i
 		}
 	}
@@ -102,21 +208,21 @@ func (cc columns) findByName(sheet, name string, optColNum ...int) int {
 
 	// name:wildcard, sheet:exact
 	for i, c := range cc {
-		if wildcardMatch(c.Name, name) && strings.EqualFold(c.Sheet, sheet) {
+		if c.nameMatch(name) && strings.EqualFold(c.Sheet, sheet) {
 			return i
 		}
 	}
 
 	// name:wildcard, sheet:wildcard
 	for i, c := range cc {
-		if wildcardMatch(c.Name, name) && wildcardMatch(c.Sheet, sheet) {
+		if c.nameMatch(name) && c.sheetMatch(sheet) {
 			return i
 		}
 	}
 
 	// name:wildcard, sheet:empty
 	for i, c := range cc {
-		if wildcardMatch(c.Name, name) && strings.EqualFold(c.Sheet, "") {
+		if c.nameMatch(name) && strings.EqualFold(c.Sheet, "") {
 			return i
 		}
 	}
@@ -124,6 +230,20 @@ func (cc columns) findByName(sheet, name string, optColNum ...int) int {
 	return -1
 }
 
+func (c column) nameMatch(name string) bool {
+	if c.nameRE != nil {
+		return c.nameRE.MatchString(name)
+	}
+	return wildcardMatch(c.Name, name)
+}
+
+func (c column) sheetMatch(sheet string) bool {
+	if c.sheetRE != nil {
+		return c.sheetRE.MatchString(sheet)
+	}
+	return wildcardMatch(c.Sheet, sheet)
+}
+
 func wildcardMatch(pattern, name string) bool {
 	if pattern == "*" {
 		return true
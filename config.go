@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/shu-go/gli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the content of --config.
+// Other keys than columns are global options named as the long names of the command line or the field names of globalCmd.
+type configFile struct {
+	Columns []columnRule  `json:"columns" yaml:"columns" toml:"columns"`
+	Sheets  []sheetConfig `json:"sheets,omitempty" yaml:"sheets,omitempty" toml:"sheets,omitempty"`
+}
+
+// loadConfig applies --config.
+// Options of the file are applied first, and then the command line is parsed over them,
+// and column and sheet rules of the file follow --columns, --header-style, --freeze, --autofilter and --start-cell.
+func (c *globalCmd) loadConfig() error {
+	if c.Config == "" {
+		return nil
+	}
+
+	name := filepath.Clean(c.Config)
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	var cfg configFile
+	var opts map[string]interface{}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		err = json.Unmarshal(content, &cfg)
+		if err == nil {
+			err = json.Unmarshal(content, &opts)
+		}
+
+	case ".toml":
+		_, err = toml.NewDecoder(bytes.NewReader(content)).Decode(&cfg)
+		if err == nil {
+			_, err = toml.NewDecoder(bytes.NewReader(content)).Decode(&opts)
+		}
+
+	default:
+		err = yaml.Unmarshal(content, &cfg)
+		if err == nil {
+			err = yaml.Unmarshal(content, &opts)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %v", name, err)
+	}
+
+	for key := range opts {
+		if strings.EqualFold(key, "columns") || strings.EqualFold(key, "sheets") {
+			delete(opts, key)
+		}
+	}
+
+	// Init applies the options after the defaults, before the command line
+	reloaded := globalCmd{cliArgs: c.cliArgs, configOptions: opts}
+	app := gli.NewWith(&reloaded)
+	app.SuppressErrorOutput = true
+	_, _, err = app.Parse(c.cliArgs)
+	if err == nil {
+		err = reloaded.configErr
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %v", name, err)
+	}
+
+	reloaded.configOptions = nil
+	reloaded.configColumns = cfg.Columns
+	reloaded.configSheets = cfg.Sheets
+	*c = reloaded
+
+	return nil
+}
+
+// Init applies options of --config being loaded, before the command line is parsed.
+func (c *globalCmd) Init() {
+	for key, value := range c.configOptions {
+		err := c.setConfigOption(key, value)
+		if err != nil {
+			c.configErr = err
+			return
+		}
+	}
+}
+
+// configScalar is a string, a number or a bool in --config.
+type configScalar string
+
+func (s *configScalar) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	return s.set(v)
+}
+
+func (s *configScalar) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: not a scalar", node.Line)
+	}
+	*s = configScalar(node.Value)
+	return nil
+}
+
+func (s *configScalar) UnmarshalTOML(v interface{}) error {
+	return s.set(v)
+}
+
+func (s *configScalar) set(v interface{}) error {
+	switch v.(type) {
+	case string, bool, float64, int64:
+		*s = configScalar(fmt.Sprint(v))
+		return nil
+	}
+	return fmt.Errorf("%v is not a scalar", v)
+}
+
+func (c *globalCmd) setConfigOption(key string, value interface{}) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		if !ft.IsExported() || !optionNamed(ft, key) {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		s := fmt.Sprint(value)
+		if choices, ok := ft.Tag.Lookup("choices"); ok {
+			found := false
+			for _, choice := range strings.Split(choices, ",") {
+				found = found || strings.EqualFold(s, choice)
+			}
+			if !found {
+				return fmt.Errorf("%v must be one of %v", key, choices)
+			}
+		}
+
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(s)
+
+		case reflect.Bool:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("%v: %v", key, err)
+			}
+			fv.SetBool(b)
+
+		case reflect.Int:
+			n, err := strconv.ParseInt(s, 10, 0)
+			if err != nil {
+				return fmt.Errorf("%v: %v", key, err)
+			}
+			fv.SetInt(n)
+
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("%v: %v", key, err)
+			}
+			fv.SetFloat(f)
+
+		case reflect.Slice:
+			if fv.Type().Elem().Kind() != reflect.String {
+				return fmt.Errorf("%v can not be configured", key)
+			}
+			list, ok := value.([]interface{})
+			if !ok {
+				list = []interface{}{value}
+			}
+			strs := make([]string, 0, len(list))
+			for _, elem := range list {
+				strs = append(strs, fmt.Sprint(elem))
+			}
+			fv.Set(reflect.ValueOf(strs))
+
+		default:
+			return fmt.Errorf("%v can not be configured", key)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown option %q", key)
+}
+
+// optionNamed reports whether the field is named key in the command line or in Go.
+func optionNamed(ft reflect.StructField, key string) bool {
+	if strings.EqualFold(ft.Name, key) {
+		return true
+	}
+
+	for _, name := range strings.Split(ft.Tag.Get("cli"), ",") {
+		name, _, _ = strings.Cut(name, "=")
+		if strings.EqualFold(strings.TrimSpace(name), key) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,21 +2,32 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
 	"log"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/shu-go/gli/v2"
 	"github.com/shu-go/gotwant"
 	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
 )
 
 func dummyCmd(args ...string) *globalCmd {
 	appargs := append([]string{"-o", "dummy"}, args...)
 	appargs = append(appargs, "dummycsvfile")
 
-	app := gli.NewWith(&globalCmd{})
+	app := gli.NewWith(&globalCmd{cliArgs: appargs})
 	icmd, _, err := app.Parse(appargs)
 	if err != nil {
 		log.Println(err)
@@ -29,6 +40,19 @@ func newInput(name, content string) input {
 	return input{Name: name, Reader: bytes.NewBufferString(content)}
 }
 
+// convertContent converts the content as test.csv into a new workbook.
+func convertContent(t *testing.T, content string, args ...string) outputContext {
+	t.Helper()
+
+	cmd := dummyCmd(args...)
+	oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{newInput("test.csv", content)}
+	err = cmd.convert(oc)
+	gotwant.TestError(t, err, nil)
+	return oc
+}
+
 func testValue(t *testing.T, oc outputContext, sheet, axis, want string, wantType ...excelize.CellType) {
 	t.Helper()
 
@@ -148,6 +172,236 @@ func TestGuess(t *testing.T) {
 	})
 }
 
+func TestNumberLocale(t *testing.T) {
+	tst := func(locale, value, wantNum, wantFormat string) {
+		t.Helper()
+
+		loc, err := lookupNumberLocale(locale)
+		gotwant.TestError(t, err, nil)
+		num, format, _, ok := parseLocaleNumber(value, loc)
+		gotwant.Test(t, ok, wantNum != "")
+		gotwant.Test(t, num, wantNum)
+		gotwant.Test(t, format, wantFormat)
+	}
+
+	tst("en-US", "1,234.56", "1234.56", "#,##0.00")
+	tst("en-US", "1234.5", "1234.5", "")
+	tst("en-US", "1,23", "", "")
+	tst("en-US", "$-1,234", "-1234", `"$"#,##0`)
+	tst("en-US", "(500)", "-500", "0;(0)")
+	tst("en-US", "500-", "-500", "0;0-")
+	tst("en-US", "USD 12.5", "12.5", `"USD "#,##0.0`)
+	tst("de-DE", "1.234,56", "1234.56", "#,##0.00")
+	tst("de-DE", "-12,5 €", "-12.5", `#,##0.0" €"`)
+	tst("fr-FR", "1\u202f234,5", "1234.5", "#,##0.0")
+	tst("de-CH", "1'234.50", "1234.50", "#,##0.00")
+	tst("ja-JP", "¥12,000", "12000", `"¥"#,##0`)
+	tst("ja-JP", "12,000円", "12000", `#,##0"円"`)
+	tst("hi-IN", "12,34,567", "1234567", "#,##0")
+	tst("en", ".5", "0.5", "")
+	tst("en", "0.5", "0.5", "")
+	tst("en-US", "SKU123", "", "")
+	tst("en-US", "ABC0123", "", "")
+	tst("en-US", "$0123", "", "")
+	tst("en-US", "0,123", "", "")
+
+	_, err := lookupNumberLocale("xx-YY")
+	gotwant.TestError(t, err, "unknown number locale")
+
+	content := "a,b\n\"1.234,5\",\"1,234.5\""
+	oc := convertContent(t, content, "--columns", "a:number(de-DE),b:number")
+	testValue(t, oc, "test.csv", "A2", "1,234.5", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "B2", "1,234.5", excelize.CellTypeSharedString)
+
+	oc = convertContent(t, content, "--columns", "a:number(locale:de-DE->0.00)", "--number-locale", "en-US")
+	testValue(t, oc, "test.csv", "A2", "1234.50", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "B2", "1,234.5", excelize.CellTypeUnset)
+
+	// product codes are not amounts
+	oc = convertContent(t, "code\nABC0123\nSKU123\n0123\nA12\nEUR 5\n", "--number-locale", "en-US")
+	testValue(t, oc, "test.csv", "A2", "ABC0123", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "A3", "SKU123", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "A4", "0123", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "A5", "A12", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "A6", "EUR 5", excelize.CellTypeUnset)
+}
+
+func TestPercentCurrency(t *testing.T) {
+	content := "rate,jpy,usd,eur,any\n" +
+		"12.5%,\"¥12,000\",$1234.5,\"1.234,5 €\",£3.20\n" +
+		"0.333,12000,(12.5),12,x\n"
+	oc := convertContent(t, content, "--columns", "rate:percent,jpy:currency(JPY),usd:currency(usd),eur:currency(EUR),any:currency")
+
+	testValue(t, oc, "test.csv", "A2", "12.50%", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "A3", "33.30%", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "B2", "¥12,000", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "B3", "¥12,000", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "C2", "$1,234.50", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "C3", "-$12.50", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "D2", "1,234.50 €", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "E2", "£3.20", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "E3", "x", excelize.CellTypeSharedString)
+
+	raw, err := oc.output.GetCellValue("test.csv", "A3", excelize.Options{RawCellValue: true})
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, raw, "0.333")
+
+	// symbols of other currencies fail, and amounts may be written with a decimal point
+	content = "jpy,eur,usd\n" +
+		"$100,\"€1,234.50\",US$5\n" +
+		"EUR 5,12.5,USD 7\n" +
+		"100円,12,€3\n"
+	oc = convertContent(t, content, "--columns", "jpy:currency(JPY),eur:currency(EUR),usd:currency(USD)")
+
+	testValue(t, oc, "test.csv", "A2", "$100", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "A3", "EUR 5", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "A4", "¥100", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "B2", "1,234.50 €", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "B3", "12.50 €", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "B4", "12.00 €", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "C2", "$5.00", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "C3", "$7.00", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "C4", "€3", excelize.CellTypeSharedString)
+
+	cmd := dummyCmd("--strict", "--columns", "jpy:currency(JPY)")
+	oc, err = cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{newInput("test.csv", "jpy\n$100\n")}
+	gotwant.TestError(t, cmd.convert(oc), "currency")
+
+	// only percent columns
+	oc = convertContent(t, "a\n5%\n")
+	testValue(t, oc, "test.csv", "A2", "5%", excelize.CellTypeSharedString)
+
+	cmd = dummyCmd("--columns", "a:currency(yen!)")
+	_, err = cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, "not a currency code")
+}
+
+func TestDuration(t *testing.T) {
+	tst := func(value, unit string, want float64, wantOK bool) {
+		t.Helper()
+
+		got, ok := parseDuration(value, unit)
+		gotwant.Test(t, ok, wantOK)
+		gotwant.Test(t, math.Round(got*86400*1000)/1000, want)
+	}
+
+	tst("37:15:00", "", 37*3600+15*60, true)
+	tst("1:30", "", 5400, true)
+	tst("0:00:01.5", "", 1.5, true)
+	tst("-2:00:00", "", -7200, true)
+	tst("PT1H30M", "", 5400, true)
+	tst("P1DT12H", "", 129600, true)
+	tst("pt0,5s", "", 0.5, true)
+	tst("P1W", "", 604800, true)
+	tst("90", "s", 90, true)
+	tst("1500", "ms", 1.5, true)
+	tst("90", "", 0, false)
+	tst("1:60", "", 0, false)
+	tst("PT", "", 0, false)
+	tst("P1Y", "", 0, false)
+	tst("1e3", "s", 0, false)
+
+	oc := convertContent(t, "a,b,c\n37:15:00,61500,PT36H\n90,x,12:30:00\n", "--columns", "a:duration,b:duration(ms->[m]:ss.000)")
+
+	testValue(t, oc, "test.csv", "A2", "37:15:00", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "A3", "0:01:30", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "B2", "1:01.500", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "B3", "x", excelize.CellTypeSharedString)
+	// only duration columns
+	testValue(t, oc, "test.csv", "C2", "PT36H", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "C3", "12:30:00")
+
+	cmd := dummyCmd("--columns", "a:duration(min)")
+	_, err := cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, "not a unit of durations")
+}
+
+func TestTimestamp(t *testing.T) {
+	tst := func(value, layout, want string, wantOK bool) {
+		t.Helper()
+
+		got, ok := parseTimestamp(value, nil, layout)
+		gotwant.Test(t, ok, wantOK)
+		if ok {
+			gotwant.Test(t, got.Format("2006-01-02 15:04:05.000000"), want)
+		}
+	}
+
+	tst("1700000000", "epoch", "2023-11-14 22:13:20.000000", true)
+	tst("1700000000.5", "epoch", "2023-11-14 22:13:20.500000", true)
+	tst("-1.5", "epoch", "1969-12-31 23:59:58.500000", true)
+	tst("1700000000123", "epoch_ms", "2023-11-14 22:13:20.123000", true)
+	tst("1700000000123456", "EPOCH_US", "2023-11-14 22:13:20.123456", true)
+	tst("45000.5", "excel_serial", "2023-03-15 12:00:00.000000", true)
+	tst("1e9", "epoch", "", false)
+	tst("-1", "excel_serial", "", false)
+	tst("1700000000", "20060102", "", false)
+
+	cmd := dummyCmd("--columns", "a:datetime(epoch_ms),b:date(excel_serial),c:datetime(epoch->yyyy-mm-dd hh:mm)")
+	oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{newInput("test.csv", "a,b,c\n1700000000123,45000.75,1700000000\nx,x,0\n")}
+	err = cmd.convert(oc)
+	gotwant.TestError(t, err, nil)
+
+	testValue(t, oc, "test.csv", "A2", "2023/11/14 22:13:20")
+	testValue(t, oc, "test.csv", "B2", "2023/03/15")
+	testValue(t, oc, "test.csv", "C2", "2023-11-14 22:13")
+	testValue(t, oc, "test.csv", "A3", "x", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "B3", "x", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "C3", "1970-01-01 00:00")
+
+	raw, err := oc.output.GetCellValue("test.csv", "B2", excelize.Options{RawCellValue: true})
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, raw, "45000")
+}
+
+func TestTZ(t *testing.T) {
+	content := "a,b,c\n" +
+		"2023-11-14T22:13:20Z,1700000000,20231114 221320\n" +
+		"2023-11-15T07:13:20+09:00,2023-11-14T22:13:20Z,x\n"
+
+	// without --tz, as written
+	oc := convertContent(t, content, "--columns", "a:datetime(2006-01-02T15:04:05Z07:00)")
+	testValue(t, oc, "test.csv", "A2", "2023/11/14 22:13:20", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "A3", "2023/11/15 07:13:20", excelize.CellTypeUnset)
+	testValue(t, oc, "test.csv", "C2", "2023/11/14 22:13:20", excelize.CellTypeUnset)
+
+	oc = convertContent(t, content, "--tz", "Asia/Tokyo", "--columns", "a:datetime(2006-01-02T15:04:05Z07:00),b:datetime(epoch)")
+	testValue(t, oc, "test.csv", "A2", "2023/11/15 07:13:20")
+	testValue(t, oc, "test.csv", "A3", "2023/11/15 07:13:20")
+	testValue(t, oc, "test.csv", "B2", "2023/11/15 07:13:20")
+	// guessed without a zone, in --tz
+	testValue(t, oc, "test.csv", "C2", "2023/11/14 22:13:20")
+
+	// tz: of a column is the zone of values without zones, displayed in --tz
+	content = "a,b\n2023/11/14 03:04,2023-11-14T22:13:20Z\n2023/11/14 22:13,2023-11-15T07:13:20+09:00\n"
+	oc = convertContent(t, content, "--tz", "Asia/Tokyo", "--columns", "a:datetime(2006/01/02 15:04 tz:UTC),b:date(2006-01-02T15:04:05Z07:00 tz:America/New_York)")
+	testValue(t, oc, "test.csv", "A2", "2023/11/14 12:04:00")
+	testValue(t, oc, "test.csv", "A3", "2023/11/15 07:13:00")
+	testValue(t, oc, "test.csv", "B2", "2023/11/15")
+	testValue(t, oc, "test.csv", "B3", "2023/11/15")
+
+	// without --tz, displayed in tz: of the column
+	oc = convertContent(t, content, "--columns", "a:datetime(2006/01/02 15:04 tz:UTC),b:datetime(2006-01-02T15:04:05Z07:00 tz:America/New_York)")
+	testValue(t, oc, "test.csv", "A2", "2023/11/14 03:04:00")
+	testValue(t, oc, "test.csv", "B2", "2023/11/14 17:13:20")
+	testValue(t, oc, "test.csv", "B3", "2023/11/14 17:13:20")
+
+	layout, zone := splitZone("2006-01-02 15:04 tz=Asia/Tokyo")
+	gotwant.Test(t, layout, "2006-01-02 15:04")
+	gotwant.Test(t, zone, "Asia/Tokyo")
+	layout, zone = splitZone("TZ:UTC")
+	gotwant.Test(t, layout, "")
+	gotwant.Test(t, zone, "UTC")
+
+	cmd := dummyCmd("--columns", "a:datetime(tz:Mars/Olympus)")
+	_, err := cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, "unknown time zone")
+}
+
 func TestMultiple(t *testing.T) {
 	cmd := dummyCmd([]string{"--header=0"}...)
 	oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
@@ -187,71 +441,1343 @@ func TestMultiple(t *testing.T) {
 	testValue(t, oc, "test3.csv", "A2", "")
 }
 
-func BenchmarkGuess(b *testing.B) {
-	tst := func(content, value string, args ...string) {
-		b.Helper()
+func TestSheetName(t *testing.T) {
+	tst := func(args ...string) []string {
+		t.Helper()
 
-		cmd := dummyCmd(append([]string{"--header=0"}, args...)...)
-		oc, _ := cmd.makeOutputContext(excelize.NewFile(), false)
+		cmd := dummyCmd(append([]string{"--columns", "q1*!a:number"}, args...)...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput(filepath.Join("data", "2026", "q1.csv"), "a\n01"),
+			newInput(filepath.Join("data", "2025", "q1.csv"), "a\n02"),
+			newInput(strings.Repeat("x", 40)+".csv", "a\n03"),
+		}
+		err = cmd.convert(oc)
+		gotwant.TestError(t, err, nil)
+
+		return oc.output.GetSheetList()
+	}
+
+	gotwant.Test(t, tst(), []string{"data_2026_q1.csv", "data_2025_q1.csv", strings.Repeat("x", 31)})
+	gotwant.Test(t, tst("--sheet-name", "{dir}_{base}"), []string{"2026_q1", "2025_q1", "_" + strings.Repeat("x", 30)})
+	gotwant.Test(t, tst("--sheet-name", "{base}"), []string{"q1", "q1_2", strings.Repeat("x", 31)})
+	gotwant.Test(t, tst("--sheet-name", "{index}:{file}"), []string{"1_q1.csv", "2_q1.csv", "3_" + strings.Repeat("x", 29)})
+
+	// hints by sheet names
+	cmd := dummyCmd("--sheet-name", "{base}", "--columns", "q1!a:number")
+	oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{newInput("q1.csv", "a\n01")}
+	gotwant.TestError(t, cmd.convert(oc), nil)
+	testValue(t, oc, "q1", "A2", "1")
+
+	gotwant.Test(t, sanitizeSheetName("'a[1]/b'"), "_a_1__b_")
+	gotwant.Test(t, expandSheetName("{name}|{ext}|{unknown}", "q1.csv", 1), "q1.csv|.csv|{unknown}")
+	gotwant.TestError(t, checkSheetName("{base}_{unknown}"), "{unknown}")
+}
+
+func TestStream(t *testing.T) {
+	content := `a,b,c,d,e,f,g
+01,11,20220101,123456,true,=1+2,abc
+,12345678901234567890,,012345,false,,`
+
+	convert := func(args ...string) outputContext {
+		t.Helper()
+
+		cmd := dummyCmd(args...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
 		oc.inputs = []input{
 			newInput("test.csv", content),
 		}
 
-		_ = cmd.convert(oc)
+		err = cmd.convert(oc)
+		gotwant.TestError(t, err, nil)
+
+		return oc
 	}
 
-	gen := func(n int) []string {
-		templ := []string{
-			"abcdefgh",
-			"01234567",
-			"12345678",
-			"20221231",
-			"012345",
-			"123456",
-			"20221231 012345",
-			"20221231 123456",
-			"true",
-			"false",
+	want := convert()
+	got := convert("--stream")
+
+	wantRows, err := want.output.GetRows("test.csv")
+	gotwant.TestError(t, err, nil)
+	gotRows, err := got.output.GetRows("test.csv")
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, gotRows, wantRows)
+
+	for _, axis := range []string{"A2", "B2", "C2", "D2", "E2", "F2", "G2", "B3", "D3", "E3"} {
+		wantValue, err := want.output.GetCellValue("test.csv", axis)
+		gotwant.TestError(t, err, nil)
+		wantType, err := want.output.GetCellType("test.csv", axis)
+		gotwant.TestError(t, err, nil)
+		if wantType == excelize.CellTypeSharedString {
+			// StreamWriter writes strings inline
+			wantType = excelize.CellTypeInlineString
 		}
-		l := len(templ)
+		testValue(t, got, "test.csv", axis, wantValue, wantType)
+	}
+}
 
-		s := make([]string, n)
-		for i := range s {
-			s[i] = templ[rand.Intn(l)]
+func TestSplit(t *testing.T) {
+	content := "a,b\n01,x1\n02,x2\n03,x3\n04,x4\n05,x5"
+
+	tst := func(args ...string) {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--split", "--split-rows", "3", "--columns", "test.csv!a:number"}, args...)...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("test.csv", content),
 		}
 
-		return s
+		err = cmd.convert(oc)
+		gotwant.TestError(t, err, nil)
+
+		gotwant.Test(t, oc.output.GetSheetList(), []string{"test.csv", "test.csv (2)", "test.csv (3)"})
+
+		testValue(t, oc, "test.csv", "A1", "a")
+		testValue(t, oc, "test.csv", "A2", "1")
+		testValue(t, oc, "test.csv", "B3", "x2")
+		testValue(t, oc, "test.csv", "A4", "")
+		testValue(t, oc, "test.csv (2)", "A1", "a")
+		testValue(t, oc, "test.csv (2)", "A2", "3")
+		testValue(t, oc, "test.csv (2)", "B3", "x4")
+		testValue(t, oc, "test.csv (3)", "B1", "b")
+		testValue(t, oc, "test.csv (3)", "A2", "5")
+		testValue(t, oc, "test.csv (3)", "A3", "")
 	}
 
-	b.Run("10", func(b *testing.B) {
-		s := strings.Join(gen(00), "\n")
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			tst(s, "")
+	tst()
+	tst("--stream")
+
+	gotwant.Test(t, splitSheetName("test.csv", 2), "test.csv (2)")
+	gotwant.Test(t, splitSheetName(strings.Repeat("a", 31), 12), strings.Repeat("a", 26)+" (12)")
+}
+
+func TestMerge(t *testing.T) {
+	tst := func(inputs []input, args ...string) (outputContext, error) {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--merge-into", "all"}, args...)...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = inputs
+
+		return oc, cmd.convert(oc)
+	}
+
+	t.Run("Same", func(t *testing.T) {
+		for _, stream := range []bool{false, true} {
+			args := []string{"--source-column", "file"}
+			if stream {
+				args = append(args, "--stream")
+			}
+
+			oc, err := tst([]input{
+				newInput("a.csv", "id,name\n1,aaa\n2,bbb"),
+				newInput("b.csv", "ID,Name\n3,ccc"),
+			}, args...)
+			gotwant.TestError(t, err, nil)
+
+			gotwant.Test(t, oc.output.GetSheetList(), []string{"all"})
+			rows, err := oc.output.GetRows("all")
+			gotwant.TestError(t, err, nil)
+			gotwant.Test(t, rows, [][]string{
+				{"file", "id", "name"},
+				{"a.csv", "1", "aaa"},
+				{"a.csv", "2", "bbb"},
+				{"b.csv", "3", "ccc"},
+			})
+			testValue(t, oc, "all", "B4", "3", excelize.CellTypeUnset)
 		}
 	})
 
-	b.Run("100", func(b *testing.B) {
-		s := strings.Join(gen(100), "\n")
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			tst(s, "")
-		}
+	t.Run("Mismatch", func(t *testing.T) {
+		_, err := tst([]input{
+			newInput("a.csv", "id,name\n1,aaa"),
+			newInput("b.csv", "id,memo\n2,bbb"),
+		})
+		gotwant.TestError(t, err, "does not match")
 	})
 
-	b.Run("1000", func(b *testing.B) {
-		s := strings.Join(gen(1000), "\n")
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			tst(s, "")
-		}
+	t.Run("Union", func(t *testing.T) {
+		oc, err := tst([]input{
+			newInput("a.csv", "id,name\n1,aaa"),
+			newInput("b.csv", "memo,ID\nccc,2"),
+			newInput("c.csv", "name\nddd"),
+		}, "--merge-union")
+		gotwant.TestError(t, err, nil)
+
+		rows, err := oc.output.GetRows("all")
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, rows, [][]string{
+			{"id", "name", "memo"},
+			{"1", "aaa"},
+			{"2", "", "ccc"},
+			{"", "ddd"},
+		})
 	})
+}
 
-	b.Run("10000", func(b *testing.B) {
-		s := strings.Join(gen(10000), "\n")
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			tst(s, "")
-		}
+func TestAppend(t *testing.T) {
+	f := excelize.NewFile()
+	tst := func(inputs []input, args ...string) (outputContext, error) {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--append", "--columns", "amount:number"}, args...)...)
+		oc, err := cmd.makeOutputContext(f, true)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = inputs
+
+		return oc, cmd.convert(oc)
+	}
+	rows := func(oc outputContext, sheet string) [][]string {
+		t.Helper()
+
+		rows, err := oc.output.GetRows(sheet)
+		gotwant.TestError(t, err, nil)
+		return rows
+	}
+
+	_, err := tst([]input{newInput("log.csv", "id,amount\n1,100")}, "--table")
+	gotwant.TestError(t, err, nil)
+	oc, err := tst([]input{newInput("log.csv", "ID,Amount\n2,200\n3,300")}, "--table")
+	gotwant.TestError(t, err, nil)
+
+	gotwant.Test(t, rows(oc, "log.csv"), [][]string{{"id", "amount"}, {"1", "100"}, {"2", "200"}, {"3", "300"}})
+	testValue(t, oc, "log.csv", "B4", "300", excelize.CellTypeUnset)
+	tables, err := oc.output.GetTables("log.csv")
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, len(tables), 1)
+	gotwant.Test(t, tables[0].Range, "A1:B4")
+
+	_, err = tst([]input{newInput("log.csv", "id,memo\n4,x")})
+	gotwant.TestError(t, err, "does not match")
+
+	// a new sheet
+	oc, err = tst([]input{newInput("new.csv", "id,amount\n1,100")})
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, rows(oc, "new.csv"), [][]string{{"id", "amount"}, {"1", "100"}})
+
+	t.Run("Merge", func(t *testing.T) {
+		_, err := tst([]input{newInput("a.csv", "id,amount\n1,100")}, "--merge-into", "all")
+		gotwant.TestError(t, err, nil)
+		oc, err := tst([]input{newInput("b.csv", "memo,id\nx,2")}, "--merge-into", "all", "--merge-union")
+		gotwant.TestError(t, err, nil)
+
+		gotwant.Test(t, rows(oc, "all"), [][]string{{"id", "amount", "memo"}, {"1", "100"}, {"2", "", "x"}})
+
+		_, err = tst([]input{newInput("c.csv", "id\n3")}, "--merge-into", "all")
+		gotwant.TestError(t, err, "does not match")
 	})
 }
+
+func TestTemplate(t *testing.T) {
+	template := func() *excelize.File {
+		t.Helper()
+
+		f := excelize.NewFile()
+		f.SetSheetName("Sheet1", "Cover")
+		_, err := f.NewSheet("Data")
+		gotwant.TestError(t, err, nil)
+		gotwant.TestError(t, f.SetCellValue("Data", "A1", "Report"), nil)
+		gotwant.TestError(t, f.SetSheetRow("Data", "B3", &[]interface{}{"id", "amount"}), nil)
+		gotwant.TestError(t, f.AddTable("Data", &excelize.Table{Range: "B3:C4", Name: "Sales", StyleName: "TableStyleLight9"}), nil)
+		return f
+	}
+
+	tst := func(f *excelize.File, inputs []input, args ...string) (outputContext, error) {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--template", "template.xlsx", "--columns", "amount:number"}, args...)...)
+		oc, err := cmd.makeOutputContext(f, true)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = inputs
+
+		return oc, cmd.convert(oc)
+	}
+
+	oc, err := tst(template(), []input{
+		newInput("data/sales.csv", "id,amount\n1,100\n2,200"),
+	}, "--into", "sales.csv->Data!B3", "--table")
+	gotwant.TestError(t, err, nil)
+
+	gotwant.Test(t, oc.output.GetSheetList(), []string{"Cover", "Data"})
+	testValue(t, oc, "Data", "A1", "Report")
+	testValue(t, oc, "Data", "B3", "id")
+	testValue(t, oc, "Data", "C5", "200", excelize.CellTypeUnset)
+	tables, err := oc.output.GetTables("Data")
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, len(tables), 1)
+	gotwant.Test(t, tables[0].Name, "Sales")
+	gotwant.Test(t, tables[0].Range, "B3:C5")
+	gotwant.Test(t, tables[0].StyleName, "TableStyleLight9")
+
+	// rows of the previous conversion are cleared
+	oc, err = tst(oc.output, []input{
+		newInput("data/sales.csv", "id,amount\n3,300"),
+	}, "--into", "sales.csv->Data!B3", "--table")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "Data", "A1", "Report")
+	testValue(t, oc, "Data", "C4", "300", excelize.CellTypeUnset)
+	testValue(t, oc, "Data", "B5", "")
+	testValue(t, oc, "Data", "C5", "")
+	tables, err = oc.output.GetTables("Data")
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, tables[0].Range, "B3:C4")
+
+	// placeholder rows, as wide as the header
+	f := template()
+	gotwant.TestError(t, f.SetSheetRow("Data", "B5", &[]interface{}{0, 0, "note"}), nil)
+	oc, err = tst(f, []input{
+		newInput("sales.csv", "id,amount\n1,100"),
+	}, "--into", "Data!B3")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "Data", "C4", "100", excelize.CellTypeUnset)
+	testValue(t, oc, "Data", "B5", "")
+	testValue(t, oc, "Data", "C5", "")
+	testValue(t, oc, "Data", "D5", "note")
+
+	// without a table
+	oc, err = tst(template(), []input{
+		newInput("sales.csv", "id,amount\n1,100"),
+	}, "--into", "Data!D10", "--freeze", "0")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "Data", "B3", "id")
+	testValue(t, oc, "Data", "E11", "100", excelize.CellTypeUnset)
+	panes, err := oc.output.GetPanes("Data")
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, panes.TopLeftCell, "A11")
+
+	_, err = tst(template(), []input{
+		newInput("a.csv", "id\n1"),
+		newInput("b.csv", "id\n2"),
+	}, "--into", "Data")
+	gotwant.TestError(t, err, "the same sheet")
+
+	rules, err := parseIntoRules([]string{"a!b", "*.csv->x!c2"})
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, rules, intoRules{{Sheet: "a!b"}, {Input: "*.csv", Sheet: "x", Cell: "C2"}})
+}
+
+func TestStartCell(t *testing.T) {
+	tst := func(args ...string) (outputContext, error) {
+		t.Helper()
+
+		f := excelize.NewFile()
+		_, err := f.NewSheet("report.csv")
+		gotwant.TestError(t, err, nil)
+		gotwant.TestError(t, f.SetCellValue("report.csv", "A1", "Title"), nil)
+
+		cmd := dummyCmd(append([]string{"--columns", "amount:number"}, args...)...)
+		oc, err := cmd.makeOutputContext(f, true)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("report.csv", "id,amount\n1,100\n2,200"),
+			newInput("other.csv", "id,amount\n3,300"),
+		}
+
+		return oc, cmd.convert(oc)
+	}
+
+	oc, err := tst("--start-cell", "report.csv!C5", "--autofilter", "on", "--freeze", "1")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "report.csv", "A1", "Title")
+	testValue(t, oc, "report.csv", "C5", "id")
+	testValue(t, oc, "report.csv", "D7", "200", excelize.CellTypeUnset)
+	testValue(t, oc, "other.csv", "A2", "3")
+	filter := ""
+	for _, name := range oc.output.GetDefinedName() {
+		if name.Name == "_xlnm._FilterDatabase" && name.Scope == "report.csv" {
+			filter = name.RefersTo
+		}
+	}
+	gotwant.Test(t, filter, "'report.csv'!$C$5:$D$7")
+	panes, err := oc.output.GetPanes("report.csv")
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, panes.TopLeftCell, "D6")
+
+	// --split-rows counts the rows above the origin
+	oc, err = tst("--start-cell", "report.csv!C5", "--split", "--split-rows", "6")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "report.csv", "D6", "100", excelize.CellTypeUnset)
+	testValue(t, oc, "report.csv", "D7", "")
+	testValue(t, oc, "report.csv (2)", "A1", "id")
+	testValue(t, oc, "report.csv (2)", "B2", "200", excelize.CellTypeUnset)
+
+	oc, err = tst("--start-cell", "report.csv!C5", "--merge-into", "report.csv", "--table")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "report.csv", "A1", "Title")
+	testValue(t, oc, "report.csv", "D8", "300", excelize.CellTypeUnset)
+	tables, err := oc.output.GetTables("report.csv")
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, tables[0].Range, "C5:D8")
+
+	// rows of the previous conversion are cleared, but not what is around the origin
+	oc, err = tst("--start-cell", "report.csv!B2")
+	gotwant.TestError(t, err, nil)
+	cmd := dummyCmd("--columns", "amount:number", "--start-cell", "report.csv!B2")
+	oc, err = cmd.makeOutputContext(oc.output, true)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{newInput("report.csv", "id,amount\n3,300")}
+	gotwant.TestError(t, cmd.convert(oc), nil)
+	testValue(t, oc, "report.csv", "A1", "Title")
+	testValue(t, oc, "report.csv", "C3", "300", excelize.CellTypeUnset)
+	testValue(t, oc, "report.csv", "B4", "")
+	testValue(t, oc, "report.csv", "C4", "")
+
+	// a StreamWriter writes sheets from scratch
+	_, err = tst("--start-cell", "report.csv!C5", "--stream")
+	gotwant.TestError(t, err, "--stream")
+	oc, err = tst("--start-cell", "other.csv!B2", "--stream")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "other.csv", "B2", "id")
+	testValue(t, oc, "other.csv", "C3", "300", excelize.CellTypeUnset)
+}
+
+func TestTable(t *testing.T) {
+	type table struct {
+		Sheet, Name, Range, Style string
+	}
+
+	tables := func(f *excelize.File) []table {
+		t.Helper()
+
+		var got []table
+		for _, sheet := range f.GetSheetList() {
+			tt, err := f.GetTables(sheet)
+			gotwant.TestError(t, err, nil)
+			for _, tbl := range tt {
+				got = append(got, table{sheet, tbl.Name, tbl.Range, tbl.StyleName})
+			}
+		}
+		return got
+	}
+
+	tst := func(f *excelize.File, overwriting bool, args ...string) outputContext {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--table"}, args...)...)
+		oc, err := cmd.makeOutputContext(f, overwriting)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("test.csv", "a,,a\n1,2,3\n4,5,6"),
+			newInput("1st.csv", "x\n1"),
+		}
+
+		err = cmd.convert(oc)
+		gotwant.TestError(t, err, nil)
+
+		return oc
+	}
+
+	for _, args := range [][]string{nil, {"--stream"}} {
+		oc := tst(excelize.NewFile(), false, args...)
+		gotwant.Test(t, tables(oc.output), []table{
+			{"test.csv", "test.csv", "A1:C3", "TableStyleMedium2"},
+			{"1st.csv", "_1st.csv", "A1:A2", "TableStyleMedium2"},
+		})
+		testValue(t, oc, "test.csv", "B1", "Column2")
+		testValue(t, oc, "test.csv", "C1", "a2")
+
+		// converted again into the same workbook
+		oc = tst(oc.output, true, append(args, "--table-style", "TableStyleLight9")...)
+		gotwant.Test(t, tables(oc.output), []table{
+			{"test.csv", "test.csv", "A1:C3", "TableStyleLight9"},
+			{"1st.csv", "_1st.csv", "A1:A2", "TableStyleLight9"},
+		})
+
+		oc = tst(excelize.NewFile(), false, append(args, "--split", "--split-rows", "2")...)
+		gotwant.Test(t, tables(oc.output), []table{
+			{"test.csv", "test.csv", "A1:C2", "TableStyleMedium2"},
+			{"1st.csv", "_1st.csv", "A1:A2", "TableStyleMedium2"},
+			{"test.csv (2)", "test.csv__2_", "A1:C2", "TableStyleMedium2"},
+		})
+	}
+
+	used := map[string]bool{"sales": true}
+	gotwant.Test(t, tableName("Sales", used), "Sales_2")
+	gotwant.Test(t, tableName("Sales", used), "Sales_3")
+	gotwant.Test(t, tableName("A1", used), "_A1")
+	gotwant.Test(t, tableName("r", used), "_r")
+	gotwant.Test(t, tableName("売上 2022", used), "売上_2022")
+}
+
+func TestLayout(t *testing.T) {
+	tst := func(args ...string) (outputContext, error) {
+		t.Helper()
+
+		cmd := dummyCmd(args...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("test.csv", "a,b\n1,2\n3,4"),
+			newInput("other.csv", "x,y,z\n1,2,3"),
+		}
+
+		return oc, cmd.convert(oc)
+	}
+
+	bold := func(oc outputContext, sheet, axis string) bool {
+		t.Helper()
+
+		id, err := oc.output.GetCellStyle(sheet, axis)
+		gotwant.TestError(t, err, nil)
+		style, err := oc.output.GetStyle(id)
+		gotwant.TestError(t, err, nil)
+		return style.Font != nil && style.Font.Bold
+	}
+
+	panes := func(oc outputContext, sheet string) string {
+		t.Helper()
+
+		p, err := oc.output.GetPanes(sheet)
+		gotwant.TestError(t, err, nil)
+		if !p.Freeze {
+			return ""
+		}
+		return fmt.Sprintf("%d,%d,%v", p.XSplit, p.YSplit, p.TopLeftCell)
+	}
+
+	autofilter := func(oc outputContext, sheet string) string {
+		t.Helper()
+
+		for _, name := range oc.output.GetDefinedName() {
+			if name.Name == "_xlnm._FilterDatabase" && name.Scope == sheet {
+				return name.RefersTo
+			}
+		}
+		return ""
+	}
+
+	for _, args := range [][]string{nil, {"--stream"}} {
+		oc, err := tst(append(args, "--header-style", "bold+fill:#DDEBF7+border,other.csv!italic", "--freeze", "0,other.csv!1")...)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, bold(oc, "test.csv", "B1"), true)
+		gotwant.Test(t, bold(oc, "test.csv", "B2"), false)
+		gotwant.Test(t, bold(oc, "other.csv", "A1"), false)
+		gotwant.Test(t, panes(oc, "test.csv"), "0,1,A2")
+		gotwant.Test(t, panes(oc, "other.csv"), "1,1,B2")
+	}
+
+	oc, err := tst("--autofilter", "on,other*!off")
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, autofilter(oc, "test.csv"), "'test.csv'!$A$1:$B$3")
+	gotwant.Test(t, autofilter(oc, "other.csv"), "")
+
+	_, err = tst("--autofilter", "on", "--stream")
+	gotwant.TestError(t, err, "--autofilter is not supported with --stream")
+
+	_, err = tst("--header-style", "bold+blink")
+	gotwant.TestError(t, err, `unknown style "blink"`)
+
+	// rules of --config follow the command line
+	name := filepath.Join(t.TempDir(), "config.json")
+	err = os.WriteFile(name, []byte(`{"sheets": [{"sheet": "other.csv", "freeze": 2, "autofilter": true}, {"header-style": "bold+fill=#DDEBF7"}]}`), 0644)
+	gotwant.TestError(t, err, nil)
+	cmd := dummyCmd("--config", name, "--freeze", "0")
+	gotwant.TestError(t, cmd.loadConfig(), nil)
+	oc, err = cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{
+		newInput("test.csv", "a,b\n1,2\n3,4"),
+		newInput("other.csv", "x,y,z\n1,2,3"),
+	}
+	err = cmd.convert(oc)
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, bold(oc, "other.csv", "A1"), true)
+	gotwant.Test(t, panes(oc, "test.csv"), "0,1,A2")
+	gotwant.Test(t, panes(oc, "other.csv"), "2,1,C2")
+	gotwant.Test(t, autofilter(oc, "test.csv"), "")
+	gotwant.Test(t, autofilter(oc, "other.csv"), "'other.csv'!$A$1:$C$2")
+}
+
+func TestAutofit(t *testing.T) {
+	tst := func(args ...string) outputContext {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--autofit", "--columns", `amount:number(->#\,##0.00)`}, args...)...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("test.csv", "id,amount,date,備考\n1,1234567,20220101,日本語テキスト\n2,1,20220102,x"),
+		}
+
+		err = cmd.convert(oc)
+		gotwant.TestError(t, err, nil)
+
+		return oc
+	}
+
+	widths := func(oc outputContext) []float64 {
+		t.Helper()
+
+		var got []float64
+		for _, col := range []string{"A", "B", "C", "D"} {
+			w, err := oc.output.GetColWidth("test.csv", col)
+			gotwant.TestError(t, err, nil)
+			got = append(got, w)
+		}
+		return got
+	}
+
+	// 1,234,567.00 / 2022/01/01 / wide characters of 2
+	gotwant.Test(t, widths(tst()), []float64{4, 14, 12, 16})
+	gotwant.Test(t, widths(tst("--autofit-min", "6", "--autofit-max", "13")), []float64{6, 13, 12, 13})
+	// filter buttons
+	gotwant.Test(t, widths(tst("--table")), []float64{6, 14, 12, 16})
+
+	gotwant.Test(t, textWidth("abc\nあいうえ"), 8)
+}
+
+func TestXlsx2csv(t *testing.T) {
+	cmd := dummyCmd("--columns", `amount:number(->#\,##0.00),when:datetime`)
+	oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{
+		newInput("test.csv", "id,amount,date,when\n1,1234.5,20220102,20220102 030405\n2,,,"),
+	}
+	err = cmd.convert(oc)
+	gotwant.TestError(t, err, nil)
+
+	tst := func(x xlsx2csvCmd, args ...string) string {
+		t.Helper()
+
+		buf := &bytes.Buffer{}
+		err := x.export(buf, *dummyCmd(args...), oc.output, "test.csv")
+		gotwant.TestError(t, err, nil)
+		return buf.String()
+	}
+
+	gotwant.Test(t, tst(xlsx2csvCmd{}), "id,amount,date,when\n1,\"1,234.50\",2022/01/02,2022/01/02 03:04:05\n2,,,\n")
+	gotwant.Test(t, tst(xlsx2csvCmd{Raw: true}, "-d", `\t`), "id\tamount\tdate\twhen\n1\t1234.5\t44563\t44563.12783564815\n2\t\t\t\n")
+	gotwant.Test(t, tst(xlsx2csvCmd{Raw: true, Date: "yyyy-mm-dd", Datetime: "yyyy-mm-dd hh:mm"}), "id,amount,date,when\n1,1234.5,2022-01-02,2022-01-02 03:04\n2,,,\n")
+
+	gotwant.Test(t, numFmtKind(`yyyy"年"m"月"d"日"`), typeDate)
+	gotwant.Test(t, numFmtKind(`[h]:mm:ss`), typeUnknown)
+	gotwant.Test(t, numFmtKind(`[$-409]h:mm AM/PM;@`), typeTime)
+	gotwant.Test(t, numFmtKind(`#,##0.00;[Red]-#,##0.00`), typeUnknown)
+
+	used := make(map[string]bool)
+	gotwant.Test(t, csvFileName("test.csv", used), "test.csv")
+	gotwant.Test(t, csvFileName("Test", used), "Test_2.csv")
+	gotwant.Test(t, csvFileName("a<b>", used), "a_b_.csv")
+}
+
+func TestEncoding(t *testing.T) {
+	tst := func(content []byte, args ...string) {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--columns", "番号:number"}, args...)...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			{Name: "test.csv", Reader: bytes.NewReader(content)},
+		}
+
+		err = cmd.convert(oc)
+		gotwant.TestError(t, err, nil)
+
+		testValue(t, oc, "test.csv", "A1", "番号")
+		testValue(t, oc, "test.csv", "B1", "名前")
+		testValue(t, oc, "test.csv", "A2", "1")
+		testValue(t, oc, "test.csv", "B2", "いろは")
+	}
+
+	content := "番号,名前\n01,いろは\n"
+	encode := func(e encoding.Encoding) []byte {
+		t.Helper()
+
+		b, err := e.NewEncoder().Bytes([]byte(content))
+		gotwant.TestError(t, err, nil)
+		return b
+	}
+
+	t.Run("UTF8", func(t *testing.T) {
+		tst([]byte(content))
+		tst([]byte(content), "--encoding", "utf-8")
+		tst(encode(unicode.UTF8BOM))
+		tst(encode(unicode.UTF8BOM), "--encoding", "utf-8")
+	})
+
+	t.Run("ShiftJIS", func(t *testing.T) {
+		tst(encode(japanese.ShiftJIS))
+		tst(encode(japanese.ShiftJIS), "--encoding", "shift_jis")
+		tst(encode(japanese.ShiftJIS), "--encoding", "sjis")
+	})
+
+	t.Run("EUCJP", func(t *testing.T) {
+		tst(encode(japanese.EUCJP))
+		tst(encode(japanese.EUCJP), "--encoding", "euc-jp")
+	})
+
+	t.Run("UTF16", func(t *testing.T) {
+		tst(encode(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)))
+		tst(encode(unicode.UTF16(unicode.BigEndian, unicode.UseBOM)))
+		tst(encode(unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)), "--encoding", "utf-16le")
+		tst(encode(unicode.UTF16(unicode.BigEndian, unicode.UseBOM)), "--encoding", "utf-16le")
+	})
+
+	_, err := lookupEncoding("no-such-encoding")
+	gotwant.TestError(t, err, "unknown encoding")
+}
+
+func TestDelimiter(t *testing.T) {
+	t.Run("Auto", func(t *testing.T) {
+		cmd := dummyCmd("-d", "auto")
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("comma.csv", "a,b;c\n1,2;3\n4,5;6"),
+			newInput("tab.tsv", "a\tb\tc\n1\t2,3\t4\n5\t6\t7"),
+			newInput("semicolon.csv", "a;b;c\n1;2,3;4\n5;6;7"),
+			newInput("pipe.csv", "a|b\n1|12:34:56\n4|12:34:56"),
+			newInput("colon.csv", "a:b\n1:2\n3:4"),
+			newInput("quoted.csv", "a;b\n\"1;2\";3\n\"4\n5\";6"),
+			newInput("lazy.csv", "a\tb\n1\"\t2\n3\t4\"\"5"),
+			newInput("single.csv", "a\n1\n2"),
+		}
+
+		err = cmd.convert(oc)
+		gotwant.TestError(t, err, nil)
+
+		testValue(t, oc, "comma.csv", "B2", "2;3")
+		testValue(t, oc, "tab.tsv", "B2", "2,3")
+		testValue(t, oc, "semicolon.csv", "B2", "2,3")
+		testValue(t, oc, "pipe.csv", "B2", "12:34:56")
+		testValue(t, oc, "colon.csv", "B2", "2")
+		testValue(t, oc, "quoted.csv", "A2", "1;2")
+		testValue(t, oc, "quoted.csv", "A3", "4\n5")
+		testValue(t, oc, "lazy.csv", "A2", "1\"")
+		testValue(t, oc, "lazy.csv", "B3", "4\"\"5")
+		testValue(t, oc, "single.csv", "A2", "1")
+	})
+
+	t.Run("Tab", func(t *testing.T) {
+		cmd := dummyCmd("-d", `\t`)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("tab.tsv", "a\tb,c\n1\t2,3"),
+		}
+
+		err = cmd.convert(oc)
+		gotwant.TestError(t, err, nil)
+
+		testValue(t, oc, "tab.tsv", "B2", "2,3")
+	})
+}
+
+func TestReaderOptions(t *testing.T) {
+	tst := func(content string, args ...string) (outputContext, error) {
+		t.Helper()
+
+		cmd := dummyCmd(args...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("test.csv", content),
+		}
+
+		return oc, cmd.convert(oc)
+	}
+
+	_, err := tst("a,b\n1,x\"y")
+	gotwant.TestError(t, err, `test.csv: line 2, column 4: bare " in non-quoted-field`)
+	gotwant.TestError(t, err, csv.ErrBareQuote)
+	oc, err := tst("a,b\n1,x\"y", "--lazy-quotes")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "B2", `x"y`)
+
+	oc, err = tst("# generated\n# by someone\na,b\n1,2", "--comment", "#")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "A1", "a")
+	testValue(t, oc, "test.csv", "B2", "2")
+
+	oc, err = tst("# a;b;c\na;b\n1;2", "--comment", "#", "-d", "auto")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "B2", "2")
+
+	oc, err = tst("a, b\n1,   x", "--trim-leading-space")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "B1", "b")
+	testValue(t, oc, "test.csv", "B2", "x")
+
+	_, err = tst("a,b\n1,2,3")
+	gotwant.TestError(t, err, "test.csv: line 2, column 1: wrong number of fields")
+	gotwant.TestError(t, err, csv.ErrFieldCount)
+	oc, err = tst("a,b\n1,2,3", "--fields-per-record", "-1")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "C2", "3")
+	_, err = tst("a,b,c\n1,2,3", "--fields-per-record", "2")
+	gotwant.TestError(t, err, "test.csv: line 1, column 1: wrong number of fields")
+}
+
+func TestOnError(t *testing.T) {
+	tst := func(args ...string) (outputContext, error) {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--columns", "n:number,d:date(d-m-y)"}, args...)...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("test.csv", "n,d\n1,4-3-22\nx\"y,4-3-22\nN/A,4-3-22\n3,2022-03-04\n4,4-3-22,z"),
+		}
+
+		return oc, cmd.convert(oc)
+	}
+
+	_, err := tst()
+	gotwant.TestError(t, err, `test.csv: line 3, column 2: bare " in non-quoted-field`)
+
+	oc, err := tst("--on-error", "skip")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "A2", "1")
+	testValue(t, oc, "test.csv", "A3", "N/A")
+	testValue(t, oc, "test.csv", "B4", "2022-03-04")
+	testValue(t, oc, "test.csv", "A5", "")
+	idx, _ := oc.output.GetSheetIndex(errorsSheetName)
+	gotwant.Test(t, idx, -1)
+
+	oc, err = tst("--on-error", "collect")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "A3", "N/A")
+	rows, err := oc.output.GetRows(errorsSheetName)
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, rows, [][]string{
+		{"Input", "Line", "Column", "Value", "Expected", "Reason"},
+		{"test.csv", "3", "", `x"y,4-3-22`, "", `bare " in non-quoted-field (column 2)`},
+		{"test.csv", "4", "n", "N/A", "number", "not a number"},
+		{"test.csv", "5", "d", "2022-03-04", "date(d-m-y or ymd->yyyy/mm/dd)", "not a date"},
+		{"test.csv", "6", "", "4,4-3-22,z", "", "wrong number of fields (column 1)"},
+	})
+
+	// the raw lines of a record over lines
+	cmd := dummyCmd("--on-error", "collect")
+	oc, err = cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{newInput("test.csv", "a,b\r\n1,\"x\r\ny\"z\r\n2,3\r\n")}
+	err = cmd.convert(oc)
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "B2", "3")
+	testValue(t, oc, errorsSheetName, "D2", "1,\"x\ny\"z")
+}
+
+func TestStrict(t *testing.T) {
+	tst := func(args ...string) (outputContext, error) {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--strict", "--columns", "n:number,d:date(d-m-y)"}, args...)...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("test.csv", "n,d,x\n1,4-3-22,a\nN/A,4-3-22,b\n3,2022-03-04,c\n4,,d"),
+		}
+
+		return oc, cmd.convert(oc)
+	}
+
+	_, err := tst()
+	gotwant.TestError(t, err, `test.csv!A3: "N/A" does not match number`)
+
+	_, err = tst("--columns", "n:text")
+	gotwant.TestError(t, err, `test.csv!B4: "2022-03-04" does not match date(d-m-y or ymd->yyyy/mm/dd)`)
+
+	oc, err := tst("--on-error", "skip")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "C2", "a")
+	testValue(t, oc, "test.csv", "C3", "d")
+	testValue(t, oc, "test.csv", "C4", "")
+
+	oc, err = tst("--on-error", "collect")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "C3", "d")
+	rows, err := oc.output.GetRows(errorsSheetName)
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, len(rows), 3)
+	gotwant.Test(t, rows[1][:4], []string{"test.csv", "3", "n", "N/A"})
+	gotwant.Test(t, rows[2][:4], []string{"test.csv", "4", "d", "2022-03-04"})
+}
+
+func TestInfer(t *testing.T) {
+	content := `zip,amount,date,flag,mixed,ratio
+01234,100,20220101,true,abc,0.5
+12345,N/A,20220102,false,1,1.5
+23456,300,20220103,true,def,0.25
+34567,400,,false,2,2
+`
+
+	tst := func(args ...string) outputContext {
+		t.Helper()
+
+		cmd := dummyCmd(append([]string{"--infer", "column"}, args...)...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("test.csv", content),
+		}
+
+		err = cmd.convert(oc)
+		gotwant.TestError(t, err, nil)
+
+		return oc
+	}
+
+	for _, args := range [][]string{nil, {"--infer-rows", "0"}, {"--stream"}} {
+		oc := tst(args...)
+
+		str := excelize.CellTypeSharedString
+		if len(args) > 0 && args[0] == "--stream" {
+			str = excelize.CellTypeInlineString
+		}
+
+		testValue(t, oc, "test.csv", "A1", "zip")
+		testValue(t, oc, "test.csv", "A2", "01234")
+		testValue(t, oc, "test.csv", "A3", "12345", str)
+		testValue(t, oc, "test.csv", "B2", "100", excelize.CellTypeUnset)
+		testValue(t, oc, "test.csv", "B3", "N/A")
+		testValue(t, oc, "test.csv", "C2", "2022/01/01")
+		testValue(t, oc, "test.csv", "D3", "FALSE")
+		testValue(t, oc, "test.csv", "E3", "1", str)
+		testValue(t, oc, "test.csv", "F2", "0.5", excelize.CellTypeUnset)
+		testValue(t, oc, "test.csv", "F5", "2")
+	}
+
+	// explicit hints win
+	oc := tst("--columns", "zip:number")
+	testValue(t, oc, "test.csv", "A2", "1234")
+
+	// the rows after the sample are guessed by the sample
+	oc = tst("--infer-rows", "2")
+	testValue(t, oc, "test.csv", "A3", "12345", excelize.CellTypeSharedString)
+	testValue(t, oc, "test.csv", "E3", "1", excelize.CellTypeSharedString)
+}
+
+func TestSchema(t *testing.T) {
+	content := `zip,amount,date,when,flag,a:b,amount
+01234,100,20220101,20220101 123456,true,x,1
+12345,N/A,20220102,20220102 123456,false,y,2
+`
+
+	tst := func(format string, args ...string) string {
+		t.Helper()
+
+		cmd := dummyCmd(args...)
+		buf := &bytes.Buffer{}
+		err := schemaCmd{Format: format}.print(buf, *cmd, []input{
+			newInput("test.csv", content),
+		})
+		gotwant.TestError(t, err, nil)
+
+		return buf.String()
+	}
+
+	gotwant.Test(t, tst("text"), `# test.csv
+test.csv!zip:text
+test.csv!amount:number
+test.csv!date:date(ymd->yyyy/mm/dd)
+test.csv!when:datetime(20060102 150405->yyyy/mm/dd hh:mm:ss)
+test.csv!flag:bool
+test.csv!#6:text
+test.csv!#7:number
+`)
+
+	gotwant.Test(t, tst("text", "--columns", "test.csv!zip:number(->#\\,##0)", "--date-xlsx", "yyyy-mm-dd"), `# test.csv
+test.csv!zip:number(->#\,##0)
+test.csv!amount:number
+test.csv!date:date(ymd->yyyy-mm-dd)
+test.csv!when:datetime(20060102 150405->yyyy/mm/dd hh:mm:ss)
+test.csv!flag:bool
+test.csv!#6:text
+test.csv!#7:number
+`)
+
+	gotwant.Test(t, tst("json"), `{
+  "columns": [
+    {
+      "sheet": "test.csv",
+      "column": "zip",
+      "type": "text"
+    },
+    {
+      "sheet": "test.csv",
+      "column": "amount",
+      "type": "number"
+    },
+    {
+      "sheet": "test.csv",
+      "column": "date",
+      "type": "date",
+      "input": "ymd",
+      "output": "yyyy/mm/dd"
+    },
+    {
+      "sheet": "test.csv",
+      "column": "when",
+      "type": "datetime",
+      "input": "20060102 150405",
+      "output": "yyyy/mm/dd hh:mm:ss"
+    },
+    {
+      "sheet": "test.csv",
+      "column": "flag",
+      "type": "bool"
+    },
+    {
+      "sheet": "test.csv",
+      "column": "#6",
+      "type": "text"
+    },
+    {
+      "sheet": "test.csv",
+      "column": "#7",
+      "type": "number"
+    }
+  ]
+}
+`)
+
+	yml := tst("yaml")
+	gotwant.Test(t, strings.HasPrefix(yml, `columns:
+  - sheet: test.csv
+    column: zip
+    type: text
+`), true, gotwant.Format("%v\n"+yml))
+
+	// round trip
+	spec := strings.Split(strings.TrimSpace(tst("text")), "\n")[1:]
+	cmd := dummyCmd("--columns", strings.Join(spec, ","))
+	oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{
+		newInput("test.csv", content),
+	}
+	err = cmd.convert(oc)
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "A2", "01234")
+	testValue(t, oc, "test.csv", "D2", "2022/01/01 12:34:56")
+	testValue(t, oc, "test.csv", "F2", "x")
+
+	// the locale of --number-locale is kept in the spec
+	buf := &bytes.Buffer{}
+	cmd = dummyCmd("--number-locale", "en-US")
+	err = schemaCmd{Format: "text"}.print(buf, *cmd, []input{
+		newInput("amt.csv", "amt\n\"1,234.5\"\n"),
+	})
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, buf.String(), "# amt.csv\namt.csv!amt:number(en-US->#\\,##0.0)\n")
+
+	spec = strings.Split(strings.TrimSpace(buf.String()), "\n")[1:]
+	cmd = dummyCmd("--columns", strings.Join(spec, ","))
+	oc, err = cmd.makeOutputContext(excelize.NewFile(), false)
+	gotwant.TestError(t, err, nil)
+	oc.inputs = []input{
+		newInput("amt.csv", "amt\n\"1,234.5\"\n"),
+	}
+	err = cmd.convert(oc)
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "amt.csv", "A2", "1,234.5", excelize.CellTypeUnset)
+}
+
+func TestColumnsOrder(t *testing.T) {
+	tst := func(args ...string) (outputContext, string, error) {
+		t.Helper()
+
+		cmd := dummyCmd(args...)
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		if err != nil {
+			return outputContext{}, "", err
+		}
+		explain := &bytes.Buffer{}
+		oc.explain = explain
+		oc.inputs = []input{
+			newInput("test.csv", "amt_1,amt_x,a:b,memo\n0012,0034,0056,0078"),
+		}
+
+		err = cmd.convert(oc)
+		return oc, explain.String(), err
+	}
+
+	// the first matching declaration wins
+	for i := 0; i < 10; i++ {
+		oc, _, err := tst("--columns", "amt_*:text,amt_?:number,*:number")
+		gotwant.TestError(t, err, nil)
+		testValue(t, oc, "test.csv", "A2", "0012")
+		testValue(t, oc, "test.csv", "B2", "0034")
+		testValue(t, oc, "test.csv", "D2", "78")
+	}
+
+	oc, explain, err := tst("--columns", `re:^amt_\d+$:number,re:^a:b$:number,re:^TEST\.csv$!memo:number,*:text`)
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "A2", "12")
+	testValue(t, oc, "test.csv", "B2", "0034")
+	testValue(t, oc, "test.csv", "C2", "56")
+	testValue(t, oc, "test.csv", "D2", "78")
+	gotwant.Test(t, explain, `test.csv!amt_1: re:^amt_\d+$:number
+test.csv!amt_x: *:text
+test.csv!a:b: re:^a:b$:number
+test.csv!memo: re:^TEST\.csv$!memo:number
+`)
+
+	_, explain, err = tst("--columns", "memo:number", "--header", "0")
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, explain, `test.csv!$A: no declaration
+test.csv!$B: no declaration
+test.csv!$C: no declaration
+test.csv!$D: no declaration
+`)
+
+	_, _, err = tst("--columns", "re:^amt_(:number")
+	gotwant.TestError(t, err, "re:^amt_(: error parsing regexp")
+
+	// spaces around the separator
+	oc, _, err = tst("--columns", "amt_1 : number, memo: number")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "A2", "12")
+	testValue(t, oc, "test.csv", "D2", "78")
+
+	_, _, err = tst("--columns", "memo:number,amt_1")
+	gotwant.TestError(t, err, `invalid column declaration "amt_1"`)
+}
+
+func TestConfig(t *testing.T) {
+	content := "name,when,amount\nfoo,\"Jan 2, 2022\",1234\n"
+
+	tst := func(ext, config string, args ...string) (outputContext, error) {
+		t.Helper()
+
+		name := filepath.Join(t.TempDir(), "config"+ext)
+		err := os.WriteFile(name, []byte(config), 0644)
+		gotwant.TestError(t, err, nil)
+
+		cmd := dummyCmd(append([]string{"--config", name}, args...)...)
+		err = cmd.loadConfig()
+		if err != nil {
+			return outputContext{}, err
+		}
+
+		oc, err := cmd.makeOutputContext(excelize.NewFile(), false)
+		gotwant.TestError(t, err, nil)
+		oc.inputs = []input{
+			newInput("test.csv", content),
+		}
+
+		return oc, cmd.convert(oc)
+	}
+
+	oc, err := tst(".yaml", `date-xlsx: yyyy-mm-dd
+columns:
+  - column: when
+    type: date
+    input: Jan 2, 2006
+  - sheet: test.csv
+    column: amount
+    type: text
+`)
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "B2", "2022-01-02")
+	testValue(t, oc, "test.csv", "C2", "1234", excelize.CellTypeSharedString)
+
+	// the command line wins
+	oc, err = tst(".json", `{
+  "DateXlsxFmt": "yyyy-mm-dd",
+  "columns": [
+    {"column": "when", "type": "date", "input": "Jan 2, 2006", "output": "dd/mm/yyyy"}
+  ]
+}`, "--date-xlsx", "yyyy.mm.dd", "--columns", "amount:text")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "B2", "02/01/2022")
+	testValue(t, oc, "test.csv", "C2", "1234", excelize.CellTypeSharedString)
+
+	oc, err = tst(".toml", `guess = false
+`)
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "C2", "1234", excelize.CellTypeSharedString)
+
+	// the command line wins even with the default value
+	oc, err = tst(".yaml", "date-xlsx: yyyy-mm-dd\nguess: false\n", "--date-xlsx", "yyyy/mm/dd", "--guess")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "C2", "1234", excelize.CellTypeUnset)
+	oc, err = tst(".yaml", "date-xlsx: yyyy-mm-dd\ncolumns:\n  - column: when\n    type: date\n    input: Jan 2, 2006\n", "--date-xlsx", "yyyy/mm/dd")
+	gotwant.TestError(t, err, nil)
+	testValue(t, oc, "test.csv", "B2", "2022/01/02")
+
+	_, err = tst(".yaml", "unknown: 1\n")
+	gotwant.TestError(t, err, `unknown option "unknown"`)
+
+	_, err = tst(".yaml", "on-error: ignore\n")
+	gotwant.TestError(t, err, "on-error must be one of fail,skip,collect")
+}
+
+func BenchmarkGuess(b *testing.B) {
+	tst := func(content, value string, args ...string) {
+		b.Helper()
+
+		cmd := dummyCmd(append([]string{"--header=0"}, args...)...)
+		oc, _ := cmd.makeOutputContext(excelize.NewFile(), false)
+		oc.inputs = []input{
+			newInput("test.csv", content),
+		}
+
+		_ = cmd.convert(oc)
+	}
+
+	gen := func(n int) []string {
+		templ := []string{
+			"abcdefgh",
+			"01234567",
+			"12345678",
+			"20221231",
+			"012345",
+			"123456",
+			"20221231 012345",
+			"20221231 123456",
+			"true",
+			"false",
+		}
+		l := len(templ)
+
+		s := make([]string, n)
+		for i := range s {
+			s[i] = templ[rand.Intn(l)]
+		}
+
+		return s
+	}
+
+	b.Run("10", func(b *testing.B) {
+		s := strings.Join(gen(00), "\n")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tst(s, "")
+		}
+	})
+
+	b.Run("100", func(b *testing.B) {
+		s := strings.Join(gen(100), "\n")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tst(s, "")
+		}
+	})
+
+	b.Run("1000", func(b *testing.B) {
+		s := strings.Join(gen(1000), "\n")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tst(s, "")
+		}
+	})
+
+	b.Run("10000", func(b *testing.B) {
+		s := strings.Join(gen(10000), "\n")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tst(s, "")
+		}
+	})
+}
+
+// rowReader reads the header and n copies of the row, without holding them in memory.
+type rowReader struct {
+	rest []byte
+	row  string
+	n    int
+}
+
+func (r *rowReader) Read(p []byte) (int, error) {
+	for len(r.rest) == 0 {
+		if r.n == 0 {
+			return 0, io.EOF
+		}
+		r.rest = []byte(r.row)
+		r.n--
+	}
+
+	n := copy(p, r.rest)
+	r.rest = r.rest[n:]
+	return n, nil
+}
+
+// peakHeap returns the peak of the heap in use while f runs, above the heap before it.
+func peakHeap(f func()) uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	base := m.HeapInuse
+
+	done := make(chan struct{})
+	peak := make(chan uint64)
+	go func() {
+		var max uint64
+		var m runtime.MemStats
+		for {
+			runtime.ReadMemStats(&m)
+			if m.HeapInuse > max {
+				max = m.HeapInuse
+			}
+
+			select {
+			case <-done:
+				peak <- max
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+
+	f()
+	close(done)
+
+	max := <-peak
+	if max < base {
+		return 0
+	}
+	return max - base
+}
+
+// BenchmarkStream reports the peak heap of conversions, which grows with rows by cells,
+// and levels off with --stream once excelize spools rows past its chunk (16 MB) into a temporary file.
+func BenchmarkStream(b *testing.B) {
+	tst := func(b *testing.B, n int, args ...string) {
+		b.Helper()
+
+		cmd := dummyCmd(args...)
+		f := excelize.NewFile()
+		defer f.Close()
+
+		oc, _ := cmd.makeOutputContext(f, false)
+		oc.inputs = []input{{
+			Name: "test.csv",
+			Reader: &rowReader{
+				rest: []byte("a,b,c,d,e,f\n"),
+				row:  "abcdefgh,01234567,12345678,20221231,123456,true\n",
+				n:    n,
+			},
+		}}
+
+		peak := peakHeap(func() {
+			_ = cmd.convert(oc)
+		})
+		b.ReportMetric(float64(peak)/1024/1024, "peak-heap-MB")
+	}
+
+	for _, n := range []int{10000, 100000, 300000} {
+		b.Run(fmt.Sprintf("cell/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tst(b, n)
+			}
+		})
+
+		b.Run(fmt.Sprintf("stream/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tst(b, n, "--stream")
+			}
+		})
+	}
+}
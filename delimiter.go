@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// delimiterCandidates are in order of precedence.
+var delimiterCandidates = []rune{',', '\t', ';', '|', ':'}
+
+// sniffLines is the number of lines examined by -d auto.
+const sniffLines = 20
+
+func parseDelimiter(s string) rune {
+	switch {
+	case s == `\t` || strings.EqualFold(s, "tab"):
+		return '\t'
+	case s == "":
+		return ','
+	default:
+		return []rune(s)[0]
+	}
+}
+
+// sniffDelimiter detects the delimiter of r by the first lines.
+// It picks the candidate giving the most consistent field counts (more than one field),
+// and reports lazy when the lines can be parsed only with LazyQuotes.
+func sniffDelimiter(r io.Reader, comment rune) (rr io.Reader, comma rune, lazy bool) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, _ := br.Peek(sniffSize)
+
+	sample := head
+	for i, pos := 0, 0; i < sniffLines; i++ {
+		n := bytes.IndexByte(sample[pos:], '\n')
+		if n == -1 {
+			break
+		}
+		pos += n + 1
+		if i == sniffLines-1 {
+			sample = sample[:pos]
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		comma, score := ',', 0.0
+		for _, cand := range delimiterCandidates {
+			if s := delimiterScore(sample, cand, comment, lazy); s > score {
+				comma, score = cand, s
+			}
+		}
+		if score > 0 {
+			return br, comma, lazy
+		}
+	}
+
+	return br, ',', false
+}
+
+// delimiterScore is the ratio of records having the most frequent field count,
+// or 0 when the sample is not parsed or has only one field.
+func delimiterScore(sample []byte, comma, comment rune, lazy bool) float64 {
+	if comma == comment {
+		return 0
+	}
+
+	r := csv.NewReader(bytes.NewReader(sample))
+	r.Comma = comma
+	r.Comment = comment
+	r.LazyQuotes = lazy
+	r.FieldsPerRecord = -1
+
+	counts := make(map[int]int)
+	records := 0
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			var perr *csv.ParseError
+			if records > 0 && errors.As(err, &perr) && perr.Err == csv.ErrQuote {
+				// a quoted field cut off at the end of the sample
+				break
+			}
+			return 0
+		}
+
+		counts[len(fields)]++
+		records++
+	}
+
+	mode, freq := 0, 0
+	for n, f := range counts {
+		if f > freq || f == freq && n > mode {
+			mode, freq = n, f
+		}
+	}
+	if mode <= 1 {
+		return 0
+	}
+
+	return float64(freq) / float64(records)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// durationUnits are input formats of durations written as plain numbers, in seconds.
+var durationUnits = map[string]float64{
+	"s":  1,
+	"ms": 1e-3,
+}
+
+var (
+	clockDurationRE = regexp.MustCompile(`^(\d+):([0-5]\d)(?::([0-5]\d(?:\.\d+)?))?$`)
+	plainDurationRE = regexp.MustCompile(`^(?:\d+(?:\.\d*)?|\.\d+)$`)
+	isoDurationRE   = regexp.MustCompile(`^(?i:P(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?)$`)
+)
+
+func checkDurationUnit(unit string) error {
+	if _, found := durationUnits[strings.ToLower(unit)]; !found {
+		return fmt.Errorf("%q is not a unit of durations (s or ms)", unit)
+	}
+	return nil
+}
+
+// parseDuration parses an elapsed time as days of Excel.
+//
+//	h:mm[:ss[.fff]] (hours beyond 24), ISO 8601 like PT1H30M or P1DT12H, or a number in the unit (s or ms)
+//
+// Without a unit, plain numbers are not durations.
+func parseDuration(value, unit string) (float64, bool) {
+	s := strings.TrimSpace(value)
+
+	sign := 1.0
+	if rest, found := strings.CutPrefix(s, "-"); found {
+		sign, s = -1, rest
+	}
+
+	var seconds float64
+	if m := clockDurationRE.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.ParseFloat(m[1], 64)
+		min, _ := strconv.ParseFloat(m[2], 64)
+		sec := 0.0
+		if m[3] != "" {
+			sec, _ = strconv.ParseFloat(m[3], 64)
+		}
+		seconds = h*3600 + min*60 + sec
+	} else if m := isoDurationRE.FindStringSubmatch(s); m != nil && len(s) > 1 && !strings.HasSuffix(strings.ToUpper(s), "T") {
+		for i, unitSeconds := range []float64{7 * 86400, 86400, 3600, 60, 1} {
+			if m[i+1] == "" {
+				continue
+			}
+			n, _ := strconv.ParseFloat(strings.ReplaceAll(m[i+1], ",", "."), 64)
+			seconds += n * unitSeconds
+		}
+	} else if unitSeconds, found := durationUnits[strings.ToLower(unit)]; found && plainDurationRE.MatchString(s) {
+		n, _ := strconv.ParseFloat(s, 64)
+		seconds = n * unitSeconds
+	} else {
+		return 0, false
+	}
+
+	return sign * seconds / 86400, true
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// sniffSize is the size of the head of a CSV examined by --encoding=auto.
+const sniffSize = 64 * 1024
+
+// lookupEncoding returns nil for auto.
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, "auto") {
+		return nil, nil
+	}
+
+	e, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	return e, nil
+}
+
+// decodeReader returns a reader that decodes r into UTF-8 stripping a BOM.
+func decodeReader(r io.Reader, name string) (io.Reader, error) {
+	e, err := lookupEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		br := bufio.NewReaderSize(r, sniffSize)
+		head, _ := br.Peek(sniffSize)
+
+		e = sniffEncoding(head)
+		if e == nil {
+			return br, nil
+		}
+		r = br
+	}
+
+	return transform.NewReader(r, unicode.BOMOverride(e.NewDecoder())), nil
+}
+
+// sniffEncoding guesses the encoding of head by a BOM.
+// Without a BOM, it returns nil for UTF-8 (or ASCII), otherwise EUC-JP or Shift_JIS.
+func sniffEncoding(head []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xef, 0xbb, 0xbf}):
+		return unicode.UTF8BOM
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}):
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	}
+
+	if validUTF8Head(head) {
+		return nil
+	}
+
+	// Shift_JIS texts mostly contain lead bytes (0x81-0x9f) that are invalid in EUC-JP.
+	if s, err := japanese.EUCJP.NewDecoder().Bytes(head); err == nil {
+		// the last rune may be cut off
+		if _, size := utf8.DecodeLastRune(s); !bytes.ContainsRune(s[:len(s)-size], utf8.RuneError) {
+			return japanese.EUCJP
+		}
+	}
+	return japanese.ShiftJIS
+}
+
+// validUTF8Head reports whether head is valid UTF-8 except for a rune cut off at the end.
+func validUTF8Head(head []byte) bool {
+	for i := 0; i < utf8.UTFMax && len(head) > 0; i++ {
+		if utf8.Valid(head) {
+			return true
+		}
+		head = head[:len(head)-1]
+	}
+	return utf8.Valid(head)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const errorsSheetName = "_errors"
+
+// rowError is a row or a value that is not converted as expected.
+type rowError struct {
+	Input    string
+	Line     int
+	Column   string
+	Value    string
+	Expected string
+	Reason   string
+}
+
+func (c globalCmd) collectsErrors() bool {
+	return strings.EqualFold(c.OnError, "collect")
+}
+
+func (c globalCmd) collectError(oc outputContext, e rowError) {
+	if c.collectsErrors() {
+		*oc.rowErrors = append(*oc.rowErrors, e)
+	}
+}
+
+func writeErrorsSheet(f *excelize.File, rowErrors []rowError) error {
+	w, err := newSheetWriter(f, errorsSheetName, false)
+	if err != nil {
+		return err
+	}
+
+	err = writeXlsxHeader(w, 0, []string{"Input", "Line", "Column", "Value", "Expected", "Reason"}, 0)
+	if err != nil {
+		return err
+	}
+
+	for i, e := range rowErrors {
+		err := w.writeRow(i+1, []interface{}{
+			excelize.Cell{Value: e.Input},
+			excelize.Cell{Value: e.Line},
+			excelize.Cell{Value: e.Column},
+			excelize.Cell{Value: e.Value},
+			excelize.Cell{Value: e.Expected},
+			excelize.Cell{Value: e.Reason},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.flush()
+}
+
+// lineRecorder keeps lines read through it, for the raw lines of rows failing to parse.
+type lineRecorder struct {
+	r io.Reader
+
+	first int      // the line number of lines[0]
+	lines []string // the last one is being read
+}
+
+func newLineRecorder(r io.Reader) *lineRecorder {
+	return &lineRecorder{r: r, first: 1, lines: []string{""}}
+}
+
+func (lr *lineRecorder) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+
+	chunk := string(p[:n])
+	for {
+		line, rest, found := strings.Cut(chunk, "\n")
+		lr.lines[len(lr.lines)-1] += line
+		if !found {
+			break
+		}
+		lr.lines = append(lr.lines, "")
+		chunk = rest
+	}
+
+	return n, err
+}
+
+// forget drops lines before the line.
+func (lr *lineRecorder) forget(line int) {
+	n := min(line-lr.first, len(lr.lines)-1)
+	if n > 0 {
+		lr.lines = lr.lines[n:]
+		lr.first += n
+	}
+}
+
+// between returns the lines from start to end, without CRs (empty for nil).
+func (lr *lineRecorder) between(start, end int) string {
+	if lr == nil {
+		return ""
+	}
+
+	var lines []string
+	for line := max(start, lr.first); line <= end && line-lr.first < len(lr.lines); line++ {
+		lines = append(lines, strings.TrimSuffix(lr.lines[line-lr.first], "\r"))
+	}
+	return strings.Join(lines, "\n")
+}
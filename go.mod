@@ -3,10 +3,13 @@ module github.com/shu-go/csv2xlsx
 go 1.26.1
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/andrew-d/go-termutil v0.0.0-20150726205930-009166a695a2
 	github.com/shu-go/gli/v2 v2.3.0
 	github.com/shu-go/gotwant v0.0.0-20190920074605-b4f19c0bac91
 	github.com/xuri/excelize/v2 v2.10.1
+	golang.org/x/text v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -21,5 +24,4 @@ require (
 	github.com/xuri/nfp v0.0.2-0.20250530014748-2ddeb826f9a9 // indirect
 	golang.org/x/crypto v0.49.0 // indirect
 	golang.org/x/net v0.52.0 // indirect
-	golang.org/x/text v0.35.0 // indirect
 )
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+)
+
+// inferInput infers the column types of the input by sampling --infer-rows rows,
+// and returns the input rewound.
+// The sampled head is kept in memory, or the whole input is spooled into a temporary file when --infer-rows is 0.
+func (c globalCmd) inferInput(in input) (input, func(), error) {
+	cleanup := func() {}
+
+	var spool io.ReadWriter
+	if c.InferRows > 0 {
+		spool = &bytes.Buffer{}
+	} else {
+		f, err := os.CreateTemp("", "csv2xlsx-*.csv")
+		if err != nil {
+			return input{}, cleanup, err
+		}
+		cleanup = func() {
+			f.Close()
+			os.Remove(f.Name())
+		}
+		spool = f
+	}
+
+	rest := in.Reader
+	in.Reader = io.TeeReader(rest, spool)
+
+	_, tallies, err := c.sampleColumns(c.newCSVReader(in))
+	if err != nil {
+		return input{}, cleanup, err
+	}
+
+	in.Inferred = make([]derivedType, len(tallies))
+	for i := range tallies {
+		in.Inferred[i] = tallies[i].infer()
+	}
+
+	if f, ok := spool.(*os.File); ok {
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			return input{}, cleanup, err
+		}
+	}
+	in.Reader = io.MultiReader(spool, rest)
+
+	return in, cleanup, nil
+}
+
+// sampleColumns reads the header and --infer-rows rows (or all with 0),
+// and tallies guessed types of each column.
+func (c globalCmd) sampleColumns(r *csv.Reader) (header []string, tallies []typeTally, err error) {
+	r.FieldsPerRecord = -1
+
+	for csvrindex, rows := 0, 0; c.InferRows <= 0 || rows < c.InferRows; csvrindex++ {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				// reported by the conversion
+				continue
+			}
+			return nil, nil, err
+		}
+
+		if csvrindex == c.Header-1 {
+			header = fields
+		}
+		if csvrindex <= c.Header-1 {
+			continue
+		}
+
+		for len(tallies) < len(fields) {
+			tallies = append(tallies, typeTally{})
+		}
+		for cindex, value := range fields {
+			if len(value) == 0 {
+				continue
+			}
+			typ, _ := c.guess(value, column{})
+			tallies[cindex].add(typ, value)
+		}
+		rows++
+	}
+
+	return header, tallies, nil
+}
+
+// typeTally counts guessed types of values in a column.
+type typeTally struct {
+	counts map[baseType]int
+	first  map[baseType]derivedType
+	order  []baseType
+
+	// numericText is set when a number is guessed as text, like a zero-padded code or a too long number.
+	numericText bool
+}
+
+var zeroPaddedRE = regexp.MustCompile(`^\s*[+-]?0\d`)
+
+func (t *typeTally) add(typ derivedType, value string) {
+	if t.counts == nil {
+		t.counts = make(map[baseType]int)
+		t.first = make(map[baseType]derivedType)
+	}
+
+	base := typ.baseType
+	if base == typeUnknown {
+		base = typeText
+	}
+	if base == typeText {
+		if matches := longNumRE.FindStringSubmatch(value); matches != nil && matches[0] == value {
+			if zeroPaddedRE.MatchString(value) || len(matches[1])+len(matches[2]) >= 16 {
+				t.numericText = true
+			} else {
+				// such as 0.5
+				typ, base = typeNumber.derive("", ""), typeNumber
+			}
+		}
+	}
+
+	if _, found := t.first[base]; !found {
+		t.first[base] = typ
+		t.order = append(t.order, base)
+	}
+	t.counts[base]++
+}
+
+// infer picks the type of the majority.
+// A column with numbers in text is text, so that codes like ZIP codes are not partly converted into numbers.
+func (t typeTally) infer() derivedType {
+	if len(t.counts) == 0 {
+		return typeUnknown.derive("", "")
+	}
+	if t.numericText {
+		return typeText.derive("", "")
+	}
+
+	best := t.order[0]
+	for _, base := range t.order[1:] {
+		if t.counts[base] > t.counts[best] {
+			best = base
+		}
+	}
+	if best == typeText {
+		return typeText.derive("", "")
+	}
+
+	return t.first[best]
+}
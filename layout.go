@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetRule is a [SHEET!]VALUE declaration of --header-style, --freeze, --autofilter or --start-cell.
+type sheetRule struct {
+	Sheet   string
+	sheetRE *regexp.Regexp
+
+	Value string
+}
+
+// sheetRules are in the order of declaration.
+type sheetRules []sheetRule
+
+// sheetConfig is a rule of sheets in --config.
+type sheetConfig struct {
+	Sheet       string       `json:"sheet,omitempty" yaml:"sheet,omitempty" toml:"sheet,omitempty"`
+	HeaderStyle configScalar `json:"header-style,omitempty" yaml:"header-style,omitempty" toml:"header-style,omitempty"`
+	Freeze      configScalar `json:"freeze,omitempty" yaml:"freeze,omitempty" toml:"freeze,omitempty"`
+	Autofilter  configScalar `json:"autofilter,omitempty" yaml:"autofilter,omitempty" toml:"autofilter,omitempty"`
+	StartCell   configScalar `json:"start-cell,omitempty" yaml:"start-cell,omitempty" toml:"start-cell,omitempty"`
+}
+
+func newSheetRule(sheet, value string) (sheetRule, error) {
+	var err error
+	r := sheetRule{Value: strings.TrimSpace(value)}
+	r.Sheet, r.sheetRE, err = compileColumnPattern(sheet)
+	if err != nil {
+		return sheetRule{}, err
+	}
+	return r, nil
+}
+
+// parseSheetRules parses [SHEET!]VALUE declarations, followed by values of --config.
+func parseSheetRules(decls []string, configs []sheetConfig, configValue func(sheetConfig) string) (sheetRules, error) {
+	var rules sheetRules
+	for _, decl := range decls {
+		sheet, value := "", decl
+		if pos := strings.Index(decl, "!"); pos != -1 {
+			sheet, value = decl[:pos], decl[pos+1:]
+		}
+
+		r, err := newSheetRule(sheet, value)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	for _, config := range configs {
+		value := configValue(config)
+		if value == "" {
+			continue
+		}
+
+		r, err := newSheetRule(config.Sheet, value)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// find returns the value for the sheet.
+// As hints of --columns, an exact name wins over patterns, and patterns win over rules without sheets.
+func (rr sheetRules) find(sheet string) (string, bool) {
+	sheet = strings.ToLower(sheet)
+
+	for _, r := range rr {
+		if r.sheetRE == nil && r.Sheet != "" && r.Sheet == sheet {
+			return r.Value, true
+		}
+	}
+	for _, r := range rr {
+		if r.sheetRE != nil && r.sheetRE.MatchString(sheet) || r.sheetRE == nil && r.Sheet != "" && wildcardMatch(r.Sheet, sheet) {
+			return r.Value, true
+		}
+	}
+	for _, r := range rr {
+		if r.Sheet == "" && r.sheetRE == nil {
+			return r.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// sheetLayouts are the rules of --header-style, --freeze, --autofilter and --start-cell.
+type sheetLayouts struct {
+	headerStyles sheetRules
+	freezes      sheetRules
+	autofilters  sheetRules
+	startCells   sheetRules
+}
+
+// sheetLayout is how a sheet is laid out.
+type sheetLayout struct {
+	headerStyle int // 0 for none
+
+	freeze     bool
+	freezeCols int
+
+	autofilter bool
+}
+
+func (c globalCmd) parseLayouts() (sheetLayouts, error) {
+	var l sheetLayouts
+	var err error
+
+	l.headerStyles, err = parseSheetRules(c.HeaderStyle, c.configSheets, func(s sheetConfig) string { return string(s.HeaderStyle) })
+	if err != nil {
+		return sheetLayouts{}, err
+	}
+	l.freezes, err = parseSheetRules(c.Freeze, c.configSheets, func(s sheetConfig) string { return string(s.Freeze) })
+	if err != nil {
+		return sheetLayouts{}, err
+	}
+	l.autofilters, err = parseSheetRules(c.Autofilter, c.configSheets, func(s sheetConfig) string { return string(s.Autofilter) })
+	if err != nil {
+		return sheetLayouts{}, err
+	}
+	l.startCells, err = parseSheetRules(c.StartCell, c.configSheets, func(s sheetConfig) string { return string(s.StartCell) })
+	if err != nil {
+		return sheetLayouts{}, err
+	}
+
+	return l, nil
+}
+
+// layoutOf resolves the layout of the sheet (by the CSV name, so that continuation sheets are the same).
+func (c globalCmd) layoutOf(oc outputContext, sheet string) (sheetLayout, error) {
+	var l sheetLayout
+
+	if value, found := oc.layouts.headerStyles.find(sheet); found && value != "" && !isOff(value) {
+		key := "header-style:" + value
+		style, found := oc.styles[key]
+		if !found {
+			s, err := parseHeaderStyle(value)
+			if err != nil {
+				return sheetLayout{}, fmt.Errorf("--header-style: %v", err)
+			}
+			style, err = oc.output.NewStyle(s)
+			if err != nil {
+				return sheetLayout{}, fmt.Errorf("--header-style: %v", err)
+			}
+			oc.styles[key] = style
+		}
+		l.headerStyle = style
+	}
+
+	if value, found := oc.layouts.freezes.find(sheet); found && !isOff(value) {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return sheetLayout{}, fmt.Errorf("--freeze: %q is not a number of columns", value)
+		}
+		l.freeze = true
+		l.freezeCols = n
+	}
+
+	if value, found := oc.layouts.autofilters.find(sheet); found {
+		on, err := parseSwitch(value)
+		if err != nil {
+			return sheetLayout{}, fmt.Errorf("--autofilter: %v", err)
+		}
+		// a table has its own filter
+		l.autofilter = on && !c.Table
+	}
+	if l.autofilter && c.Stream {
+		return sheetLayout{}, fmt.Errorf("%v: --autofilter is not supported with --stream (use --table)", sheet)
+	}
+
+	return l, nil
+}
+
+// startCell returns the origin of the sheet by --start-cell, empty for A1.
+func (l sheetLayouts) startCell(sheet string) (string, error) {
+	value, found := l.startCells.find(sheet)
+	if !found || value == "" || isOff(value) {
+		return "", nil
+	}
+
+	col, row, err := excelize.CellNameToCoordinates(value)
+	if err != nil {
+		return "", fmt.Errorf("--start-cell: %q is not a cell", value)
+	}
+	if col == 1 && row == 1 {
+		return "", nil
+	}
+
+	return strings.ToUpper(value), nil
+}
+
+// isOff reports whether the value disables an option, not like 0 of --freeze.
+func isOff(value string) bool {
+	switch strings.ToLower(value) {
+	case "off", "no", "false":
+		return true
+	}
+	return false
+}
+
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%q is not on or off", value)
+	}
+	return on, nil
+}
+
+var colorRE = regexp.MustCompile(`^#?([0-9a-fA-F]{6})$`)
+
+// parseHeaderStyle parses STYLE+STYLE+... of --header-style.
+//
+//	bold, italic, underline, wrap, center, border, fill:RRGGBB, color:RRGGBB
+//
+// = is also accepted in place of : (the command line can not have = in values).
+func parseHeaderStyle(s string) (*excelize.Style, error) {
+	style := &excelize.Style{}
+	font := &excelize.Font{}
+	alignment := &excelize.Alignment{}
+
+	for _, token := range strings.Split(s, "+") {
+		name, value, _ := strings.Cut(strings.TrimSpace(token), ":")
+		if n, v, found := strings.Cut(name, "="); found {
+			name, value = n, v
+		}
+
+		switch strings.ToLower(name) {
+		case "bold":
+			font.Bold = true
+		case "italic":
+			font.Italic = true
+		case "underline":
+			font.Underline = "single"
+		case "wrap":
+			alignment.WrapText = true
+		case "center":
+			alignment.Horizontal = "center"
+		case "border":
+			for _, side := range []string{"left", "top", "right", "bottom"} {
+				style.Border = append(style.Border, excelize.Border{Type: side, Color: "000000", Style: 1})
+			}
+		case "fill", "color":
+			m := colorRE.FindStringSubmatch(value)
+			if m == nil {
+				return nil, fmt.Errorf("%q is not a color of RRGGBB", token)
+			}
+			if strings.EqualFold(name, "fill") {
+				style.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{m[1]}}
+			} else {
+				font.Color = m[1]
+			}
+		default:
+			return nil, fmt.Errorf("unknown style %q", token)
+		}
+	}
+
+	if *font != (excelize.Font{}) {
+		style.Font = font
+	}
+	if *alignment != (excelize.Alignment{}) {
+		style.Alignment = alignment
+	}
+
+	return style, nil
+}
+
+// panes freezes the header row (if any) and cols columns, with rows and columns before the origin.
+func (l sheetLayout) panes(row0, col0, headerRows int) *excelize.Panes {
+	xsplit, ysplit := 0, 0
+	if l.freezeCols > 0 {
+		xsplit = col0 + l.freezeCols
+	}
+	if headerRows > 0 {
+		ysplit = row0 + headerRows
+	}
+	if !l.freeze || xsplit == 0 && ysplit == 0 {
+		return nil
+	}
+
+	topLeft, _ := excelize.CoordinatesToCellName(xsplit+1, ysplit+1)
+	p := &excelize.Panes{
+		Freeze:      true,
+		XSplit:      xsplit,
+		YSplit:      ysplit,
+		TopLeftCell: topLeft,
+	}
+	switch {
+	case ysplit > 0 && xsplit > 0:
+		p.ActivePane = "bottomRight"
+	case ysplit > 0:
+		p.ActivePane = "bottomLeft"
+	default:
+		p.ActivePane = "topRight"
+	}
+	p.Selection = []excelize.Selection{{SQRef: topLeft, ActiveCell: topLeft, Pane: p.ActivePane}}
+
+	return p
+}
+
+// openSheet makes a writer of the sheet being written, freezing panes before any rows.
+func (c globalCmd) openSheet(oc outputContext, st *sheetState) (*sheetWriter, error) {
+	w, err := newSheetWriter(oc.output, st.xlsxsheet, c.Stream)
+	if err != nil {
+		return nil, err
+	}
+	w.row0, w.col0 = st.row0, st.col0
+
+	headerRows := 0
+	if c.Header > 0 {
+		headerRows = 1
+	}
+	if p := st.layout.panes(st.row0, st.col0, headerRows); p != nil {
+		err := w.setPanes(p)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", st.xlsxsheet, err)
+		}
+	}
+
+	return w, nil
+}
+
+// closeSheet adds a table or an autofilter over the header and the rows, fits columns, and flushes the writer.
+func (c globalCmd) closeSheet(oc outputContext, st *sheetState) error {
+	if c.Table && st.header != nil {
+		err := c.addTable(oc, st)
+		if err != nil {
+			return err
+		}
+	}
+
+	if st.layout.autofilter && len(st.header) > 0 {
+		rng, err := st.w.rangeName(st.rindex, len(st.header))
+		if err != nil {
+			return err
+		}
+
+		err = st.w.autoFilter(rng)
+		if err != nil {
+			return fmt.Errorf("%v: %v", st.xlsxsheet, err)
+		}
+	}
+
+	if c.Autofit {
+		err := st.widths.apply(st.w, c.AutofitMin, c.AutofitMax)
+		if err != nil {
+			return err
+		}
+	}
+
+	return st.w.flush()
+}
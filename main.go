@@ -22,12 +22,23 @@ import (
 var Version string
 
 type globalCmd struct {
-	Output    string `cli:"output,o=FILENAME" required:"true"`
-	Delimiter string `cli:"d" default:"," help:"a value delimiter"`
+	Output    string `cli:"output,o=FILENAME" help:"an XLSX file (required)"`
+	Delimiter string `cli:"d" default:"," help:"a value delimiter (\\t for tab, auto: detect comma, tab, semicolon, pipe or colon per CSV)"`
+	Encoding  string `cli:"encoding,enc=ENCODING" default:"auto" help:"the encoding of CSVs (auto, utf-8, shift_jis, euc-jp, utf-16le, utf-16be, ...)"`
+
+	LazyQuotes       bool   `cli:"lazy-quotes" help:"allow quotes in non-quoted fields and non-doubled quotes in quoted fields"`
+	Comment          string `cli:"comment=CHAR" help:"skip lines beginning with CHAR"`
+	TrimLeadingSpace bool   `cli:"trim-leading-space" help:"ignore leading white spaces of fields"`
+	FieldsPerRecord  int    `cli:"fields-per-record=N" default:"0" help:"the number of fields of each record (0: as many as the first record, -1: variable)"`
+
+	OnError string `cli:"on-error=fail|skip|collect" default:"fail" type:"Choice" choices:"fail,skip,collect" help:"on an unparsable row: fail, skip it, or collect it into the _errors sheet (collect also reports values not matching --columns)"`
+	Strict  bool   `cli:"strict" help:"treat a value not matching its --columns type as an error of the row, instead of writing it as text"`
 
 	Header int `cli:"header" default:"1" help:"-1 when no header"`
 
-	GuessType bool `cli:"guess,g" default:"true" help:"guess cell type by --columns or CSV values"`
+	GuessType bool   `cli:"guess,g" default:"true" help:"guess cell type by --columns or CSV values"`
+	Infer     string `cli:"infer=cell|column" default:"cell" type:"Choice" choices:"cell,column" help:"guess a type per cell, or one type per column by sampling rows"`
+	InferRows int    `cli:"infer-rows=N" default:"1000" help:"the rows sampled by --infer=column (0: whole CSV through a temporary file)"`
 
 	DateFmt     string `cli:"date,df" default:"ymd" help:"global input format of date over columns"`
 	TimeFmt     string `cli:"time,tf" default:"hms" help:"global input format of time over columns"`
@@ -36,15 +47,71 @@ type globalCmd struct {
 	DateXlsxFmt     string `cli:"date-xlsx,dxf" default:"yyyy/mm/dd" help:"global output format of date over columns"`
 	TimeXlsxFmt     string `cli:"time-xlsx,txf" default:"hh:mm:ss" help:"global output format of time over columns"`
 	DatetimeXlsxFmt string `cli:"datetime-xlsx,dtxf" default:"yyyy/mm/dd hh:mm:ss" help:"global output format of datetime over columns"`
+	DurationXlsxFmt string `cli:"duration-xlsx,durxf" default:"[h]:mm:ss" help:"global output format of duration over columns"`
+
+	TZ string `cli:"tz=ZONE" help:"display dates and datetimes in ZONE (Asia/Tokyo, UTC, +09:00, ...), converting those of other time zones (values without time zones are in ZONE, unless tz: of --columns)"`
 
 	NumberXlsxFmt string `cli:"number-xlsx,nxf" default:""`
+	NumberLocale  string `cli:"number-locale=LOCALE" help:"parse numbers written in LOCALE (en-US, de-DE, fr-FR, ja-JP, ...) with digit groups, currency symbols, trailing minus and parentheses"`
 
-	Columns map[string]string `cli:"columns,cols" help:"[SHEET!]COLUMN_NAME:TYPE[(INPUT_FORMAT[->OUTPUT_FORMAT])],..."`
+	Columns        columnDecls `cli:"columns,cols" help:"[SHEET!]COLUMN_NAME:TYPE[(INPUT_FORMAT[->OUTPUT_FORMAT])],... (the first matching declaration wins)"`
+	ExplainColumns bool        `cli:"explain-columns" help:"print which declaration of --columns matches each column to stderr"`
 
+	SheetName     string `cli:"sheet-name=TEMPLATE" default:"{name}" help:"the sheet name of a CSV by {name} (the path), {dir} (the parent directory), {base}, {ext}, {file} ({base}{ext}) and {index} (1, 2, ...)"`
 	PipelinedName string `cli:"pipelined-name,name=SHEET_NAME" help:"the name of a pipelined CSV" default:"Sheet1"`
+
+	Stream bool `cli:"stream" help:"write rows through a StreamWriter to keep memory usage flat (for very large CSVs)"`
+
+	Table      bool   `cli:"table" help:"register each sheet as an Excel table (requires a header)"`
+	TableStyle string `cli:"table-style=STYLE" default:"TableStyleMedium2" help:"the built-in style of --table (TableStyleLight1..21, TableStyleMedium1..28, TableStyleDark1..11)"`
+
+	HeaderStyle []string `cli:"header-style=[SHEET!]STYLE" help:"the style of header rows: STYLE+STYLE+... of bold, italic, underline, wrap, center, border, fill:RRGGBB, color:RRGGBB"`
+	Freeze      []string `cli:"freeze=[SHEET!]N" help:"freeze the header row and the first N columns (0: the header row only, off)"`
+	Autofilter  []string `cli:"autofilter=[SHEET!]on|off" help:"put a filter on the header row (not with --stream; a table of --table has its own)"`
+	StartCell   []string `cli:"start-cell=[SHEET!]CELL" help:"write the header and rows from CELL, writing over the existing sheet instead of replacing it, with the rows of a previous conversion cleared (--into wins)"`
+
+	Autofit    bool    `cli:"autofit" help:"fit the widths of columns to the widest values (not with --stream)"`
+	AutofitMin float64 `cli:"autofit-min=WIDTH" default:"4" help:"the minimum width of --autofit"`
+	AutofitMax float64 `cli:"autofit-max=WIDTH" default:"60" help:"the maximum width of --autofit"`
+
+	Template string   `cli:"template=FILE.xlsx" help:"start from FILE.xlsx when --output does not exist, writing over its sheets instead of replacing them"`
+	Into     []string `cli:"into=[CSV_NAME->]SHEET[!CELL]" help:"write CSVs into SHEET from CELL, instead of the sheets of their names"`
+
+	Append bool `cli:"append" help:"append rows beneath existing sheets of the same names with the same header, instead of replacing them (not with --stream)"`
+
+	MergeInto    string `cli:"merge-into=SHEET" help:"write all CSVs into one sheet, with the header of the first CSV"`
+	MergeUnion   bool   `cli:"merge-union" help:"with --merge-into, merge columns by header names instead of requiring the same headers"`
+	SourceColumn string `cli:"source-column=NAME" help:"add the first column NAME of the CSV names"`
+
+	Split     bool `cli:"split" help:"roll over to 'SHEET (2)', 'SHEET (3)'... when a CSV exceeds the rows of a worksheet"`
+	SplitRows int  `cli:"split-rows=N" default:"1048576" help:"the maximum rows of a sheet (including the header row) with --split"`
+
+	Config string `cli:"config=FILE" help:"a YAML, JSON or TOML file of global options and column rules (options on the command line win)"`
+
+	// cliArgs are the arguments of the command line, parsed again over options of --config.
+	cliArgs []string
+	// configOptions are global options of --config being loaded, applied by Init.
+	configOptions map[string]interface{}
+	configErr     error
+
+	// configColumns are column rules of --config, following --columns.
+	configColumns []columnRule
+	// configSheets are sheet rules of --config, following --header-style, --freeze, --autofilter and --start-cell.
+	configSheets []sheetConfig
+
+	Schema   *schemaCmd   `cli:"schema" help:"print inferred column types as --columns" usage:"csv2xlsx [options] schema [--format text|json|yaml] CSV_FILENAME [CSV_FILENAME...]"`
+	Xlsx2csv *xlsx2csvCmd `cli:"xlsx2csv" help:"export sheets of a workbook to CSVs" usage:"csv2xlsx [-d DELIMITER] [--encoding ENCODING] xlsx2csv [--sheet SHEET] [--raw] [--date FORMAT] [--dir DIR] XLSX_FILENAME"`
 }
 
-func (c globalCmd) Before(args []string) error {
+func (c *globalCmd) Before(args []string) error {
+	if err := c.loadConfig(); err != nil {
+		return err
+	}
+
+	if c.Schema != nil || c.Xlsx2csv != nil {
+		return nil
+	}
+
 	if c.Output == "" {
 		return errors.New("--output is required")
 	}
@@ -53,6 +120,62 @@ func (c globalCmd) Before(args []string) error {
 		return errors.New("at least one csv file is required")
 	}
 
+	if _, err := lookupEncoding(c.Encoding); err != nil {
+		return err
+	}
+
+	if err := checkSheetName(c.SheetName); err != nil {
+		return err
+	}
+
+	if c.NumberLocale != "" {
+		if _, err := lookupNumberLocale(c.NumberLocale); err != nil {
+			return err
+		}
+	}
+
+	if c.TZ != "" {
+		if _, err := lookupZone(c.TZ); err != nil {
+			return fmt.Errorf("--tz: %v", err)
+		}
+	}
+
+	if c.Table && c.Header < 1 {
+		return errors.New("--table requires a header (--header)")
+	}
+
+	if c.Table && !tableStyleRE.MatchString(c.TableStyle) {
+		return fmt.Errorf("unknown table style %q", c.TableStyle)
+	}
+
+	if c.Autofit && c.Stream {
+		return errors.New("--autofit is not supported with --stream")
+	}
+
+	if c.Autofit && (c.AutofitMin < 0 || c.AutofitMin > c.AutofitMax || c.AutofitMax > excelize.MaxColumnWidth) {
+		return fmt.Errorf("--autofit-min and --autofit-max must be in 0..%d", excelize.MaxColumnWidth)
+	}
+
+	if err := c.checkTemplate(); err != nil {
+		return err
+	}
+
+	if len(c.Into) > 0 && c.MergeInto != "" {
+		return errors.New("--into can not be used with --merge-into")
+	}
+
+	if c.Append && c.Stream {
+		return errors.New("--append is not supported with --stream")
+	}
+
+	if c.MergeUnion && (c.MergeInto == "" || c.Header < 1) {
+		return errors.New("--merge-union requires --merge-into and a header")
+	}
+
+	if c.Split && (c.SplitRows < 1 || c.SplitRows > excelize.TotalRows) {
+		return fmt.Errorf("--split-rows must be in 1..%d", excelize.TotalRows)
+	}
+
 	return nil
 }
 
@@ -66,9 +189,18 @@ func (c globalCmd) Run(args []string) error {
 			return err
 		}
 		xlsxfile = f
+	} else if c.Template != "" {
+		// saved as --output, leaving the template as it is
+		exists = true
+		f, err := excelize.OpenFile(filepath.Clean(c.Template))
+		if err != nil {
+			return err
+		}
+		xlsxfile = f
 	} else {
 		xlsxfile = excelize.NewFile()
 	}
+	defer xlsxfile.Close()
 
 	oc, err := c.makeOutputContext(xlsxfile, exists)
 	if err != nil {
@@ -113,6 +245,14 @@ func (c globalCmd) Run(args []string) error {
 type input struct {
 	Name   string
 	Reader io.Reader
+
+	// Comma is the delimiter of the input. 0 for -d.
+	Comma rune
+	// LazyQuotes is set when the input has bare quotes.
+	LazyQuotes bool
+
+	// Inferred is the types of the columns by --infer=column.
+	Inferred []derivedType
 }
 
 type outputContext struct {
@@ -124,29 +264,122 @@ type outputContext struct {
 	hints columns
 
 	styles map[string]int
+
+	rowErrors *[]rowError
+
+	// explain is where --explain-columns writes, or nil.
+	explain io.Writer
+
+	// tableNames are lowercased names of tables in the workbook, for --table.
+	tableNames map[string]bool
+
+	layouts sheetLayouts
+
+	intos intoRules
 }
 
-func (c globalCmd) makeOutputContext(xlsxfile *excelize.File, overwriting bool) (outputContext, error) {
-	oc := outputContext{
-		output:      xlsxfile,
-		overwriting: overwriting,
-		styles:      make(map[string]int),
-	}
+// parseHints parses --columns and column rules of --config, in the order of declaration.
+// Types of hints are derived after the implicit formats are initialized.
+func (c globalCmd) parseHints() (columns, error) {
+	initImplicitDecls(c.DateFmt, c.DateXlsxFmt, c.TimeFmt, c.TimeXlsxFmt, c.DatetimeFmt, c.DatetimeXlsxFmt, c.DurationXlsxFmt, c.NumberXlsxFmt)
+
+	var hints columns
+	for _, decl := range c.Columns {
+		if decl.Type == "" {
+			return nil, fmt.Errorf("invalid column declaration %q", decl.Key)
+		}
 
-	for k, v := range c.Columns {
-		typ, err := parseType(v)
+		typ, err := parseType(decl.Type)
 		if err != nil {
-			return outputContext{}, err
+			return nil, err
 		}
 
-		oc.hints = append(oc.hints, newColumn(k, typ))
+		col, err := newColumn(decl.Key, typ)
+		if err != nil {
+			return nil, err
+		}
+		hints = append(hints, col)
+	}
+	for _, rule := range c.configColumns {
+		typ, err := parseType(rule.Type)
+		if err != nil {
+			return nil, err
+		}
+		if rule.Input != "" {
+			typ.explicitInputFormat = rule.Input
+		}
+		if rule.Output != "" {
+			typ.explicitOutputFormat = rule.Output
+		}
+
+		col, err := newSheetColumn(rule.Sheet, rule.Column, typ)
+		if err != nil {
+			return nil, err
+		}
+		hints = append(hints, col)
 	}
 	/*
-		for _, h := range oc.hints {
+		for _, h := range hints {
 			log.Println(h)
 		}
 	*/
 
+	for _, h := range hints {
+		if h.Type.explicitInputFormat == "" {
+			continue
+		}
+
+		var err error
+		switch h.Type.baseType {
+		case typeNumber, typePercent:
+			_, err = lookupNumberLocale(localeName(h.Type.explicitInputFormat))
+		case typeCurrency:
+			_, err = lookupCurrency(h.Type.explicitInputFormat)
+		case typeDuration:
+			err = checkDurationUnit(h.Type.explicitInputFormat)
+		case typeDate, typeDatetime:
+			if _, zone := splitZone(h.Type.explicitInputFormat); zone != "" {
+				_, err = lookupZone(zone)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", h.Rule, err)
+		}
+	}
+
+	return hints, nil
+}
+
+func (c globalCmd) makeOutputContext(xlsxfile *excelize.File, overwriting bool) (outputContext, error) {
+	oc := outputContext{
+		output:      xlsxfile,
+		overwriting: overwriting,
+		styles:      make(map[string]int),
+		rowErrors:   &[]rowError{},
+	}
+
+	hints, err := c.parseHints()
+	if err != nil {
+		return outputContext{}, err
+	}
+	oc.hints = hints
+
+	layouts, err := c.parseLayouts()
+	if err != nil {
+		return outputContext{}, err
+	}
+	oc.layouts = layouts
+
+	intos, err := parseIntoRules(c.Into)
+	if err != nil {
+		return outputContext{}, err
+	}
+	oc.intos = intos
+
+	if c.ExplainColumns {
+		oc.explain = os.Stderr
+	}
+
 	style, err := defineStyle(xlsxfile, c.DateXlsxFmt)
 	if err != nil {
 		return outputContext{}, err
@@ -175,18 +408,50 @@ func (c globalCmd) makeOutputContext(xlsxfile *excelize.File, overwriting bool)
 }
 
 func (c globalCmd) convert(oc outputContext) error {
-	initImplicitDecls(c.DateFmt, c.DateXlsxFmt, c.TimeFmt, c.TimeXlsxFmt, c.DatetimeFmt, c.DatetimeXlsxFmt, c.NumberXlsxFmt)
+	initImplicitDecls(c.DateFmt, c.DateXlsxFmt, c.TimeFmt, c.TimeXlsxFmt, c.DatetimeFmt, c.DatetimeXlsxFmt, c.DurationXlsxFmt, c.NumberXlsxFmt)
+
+	var targets []sheetTarget
+	if c.MergeInto != "" {
+		t, err := c.sheetTargetOf(oc, c.MergeInto)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, t)
+	} else {
+		names := c.sheetNames(oc.inputs)
+		for i, in := range oc.inputs {
+			t, err := c.targetOf(oc, in, names[i])
+			if err != nil {
+				return err
+			}
+			for j := range targets {
+				if strings.EqualFold(targets[j].sheet, t.sheet) {
+					return fmt.Errorf("%v and %v are written into the same sheet %v (see --merge-into)", oc.inputs[j].Name, in.Name, t.sheet)
+				}
+			}
+			targets = append(targets, t)
+		}
+	}
 
-	for _, in := range oc.inputs {
-		err := c.convertOne(oc, in.Name, in.Reader)
+	// Sheets are arranged before writing any rows.
+	// Deleting or activating a sheet reads all worksheets, which defeats a StreamWriter.
+	for _, t := range targets {
+		err := c.prepareSheet(oc, t.sheet, c.keepsSheet(t))
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.collectsErrors() {
+		err := c.prepareSheet(oc, errorsSheetName, false)
 		if err != nil {
 			return err
 		}
 	}
 
 	sheet1 := false
-	for _, in := range oc.inputs {
-		sheet1 = sheet1 || strings.EqualFold(in.Name, "Sheet1")
+	for _, t := range targets {
+		sheet1 = sheet1 || strings.EqualFold(t.sheet, "Sheet1")
 	}
 
 	if !sheet1 && !oc.overwriting {
@@ -195,6 +460,51 @@ func (c globalCmd) convert(oc outputContext) error {
 
 	oc.output.SetActiveSheet(0)
 
+	if c.Table {
+		used, err := usedTableNames(oc.output)
+		if err != nil {
+			return err
+		}
+		oc.tableNames = used
+	}
+
+	if c.MergeInto != "" {
+		err := c.merge(oc, targets[0])
+		if err != nil {
+			return err
+		}
+	} else {
+		for i, in := range oc.inputs {
+			in, cleanup, err := c.openInput(in)
+			defer cleanup()
+			if err != nil {
+				return err
+			}
+
+			st, err := c.beginSheet(oc, targets[i].sheet, targets[i].cell)
+			if err != nil {
+				return err
+			}
+
+			err = c.convertOne(oc, in, st)
+			if err != nil {
+				return err
+			}
+
+			err = c.closeSheet(oc, st)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.collectsErrors() {
+		err := writeErrorsSheet(oc.output, *oc.rowErrors)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -209,47 +519,188 @@ func (c globalCmd) tempSheetName(oc outputContext) string {
 	}
 }
 
-func (c globalCmd) convertOne(oc outputContext, sheet string, input io.Reader) error {
-	tempname := c.tempSheetName(oc)
-	oc.output.NewSheet(tempname)
-	oc.output.DeleteSheet(sheet)
-	oc.output.NewSheet(sheet)
-	oc.output.DeleteSheet(tempname)
+// prepareSheet replaces the sheet with an empty one, or keeps the existing one to be written over.
+func (c globalCmd) prepareSheet(oc outputContext, sheet string, keep bool) error {
+	if idx, _ := oc.output.GetSheetIndex(sheet); keep && idx != -1 {
+		if c.Stream {
+			return fmt.Errorf("%v: an existing sheet can not be written over with --stream", sheet)
+		}
+		if c.Append {
+			// continued by resumeSheet
+			return nil
+		}
+	} else {
+		tempname := c.tempSheetName(oc)
+		oc.output.NewSheet(tempname)
+		deleteTables(oc.output, sheet)
+		oc.output.DeleteSheet(sheet)
+		oc.output.NewSheet(sheet)
+		oc.output.DeleteSheet(tempname)
+	}
+
+	if c.Split {
+		// continuation sheets of a previous conversion
+		for part := 2; ; part++ {
+			name := splitSheetName(sheet, part)
+			if idx, _ := oc.output.GetSheetIndex(name); idx == -1 {
+				break
+			}
+			deleteTables(oc.output, name)
+			oc.output.DeleteSheet(name)
+		}
+	}
+
+	return nil
+}
+
+// splitSheetName returns the name of the part-th sheet of a split CSV.
+func splitSheetName(sheet string, part int) string {
+	suffix := fmt.Sprintf(" (%d)", part)
+
+	base := []rune(sheet)
+	if max := excelize.MaxSheetNameLength - len(suffix); len(base) > max {
+		base = base[:max]
+	}
+
+	return string(base) + suffix
+}
+
+func (c globalCmd) commentRune() rune {
+	if c.Comment == "" {
+		return 0
+	}
+	return []rune(c.Comment)[0]
+}
+
+// openInput decodes the input into UTF-8, detects its delimiter with -d auto,
+// and infers its column types with --infer=column.
+// cleanup must be called after the conversion of the input.
+func (c globalCmd) openInput(in input) (_ input, cleanup func(), err error) {
+	cleanup = func() {}
+
+	r, err := decodeReader(in.Reader, c.Encoding)
+	if err != nil {
+		return input{}, cleanup, err
+	}
+	in.Reader = r
+
+	if in.Comma == 0 && strings.EqualFold(c.Delimiter, "auto") {
+		in.Reader, in.Comma, in.LazyQuotes = sniffDelimiter(in.Reader, c.commentRune())
+	}
+
+	if strings.EqualFold(c.Infer, "column") {
+		return c.inferInput(in)
+	}
+
+	return in, cleanup, nil
+}
 
-	r := csv.NewReader(input)
-	if len(c.Delimiter) > 0 {
-		r.Comma = []rune(c.Delimiter)[0]
+func (c globalCmd) newCSVReader(in input) *csv.Reader {
+	r := csv.NewReader(in.Reader)
+	if in.Comma != 0 {
+		r.Comma = in.Comma
+	} else if len(c.Delimiter) > 0 {
+		r.Comma = parseDelimiter(c.Delimiter)
+	}
+	r.LazyQuotes = c.LazyQuotes || in.LazyQuotes
+	r.Comment = c.commentRune()
+	r.TrimLeadingSpace = c.TrimLeadingSpace
+	r.FieldsPerRecord = c.FieldsPerRecord
+	return r
+}
+
+// convertOne writes rows of the input into the sheet.
+// The header is written unless the sheet has one (with --merge-into).
+func (c globalCmd) convertOne(oc outputContext, in input, st *sheetState) error {
+	sheet := st.sheet
+
+	var lines *lineRecorder
+	if c.collectsErrors() {
+		lines = newLineRecorder(in.Reader)
+		in.Reader = lines
+	}
+	r := c.newCSVReader(in)
+
+	offset := 0
+	if c.SourceColumn != "" {
+		offset = 1
 	}
 
 	csvrindex := 0
-	xlsxrindex := 0
 	columns := []string{}
+	var mapping []int // columns of the sheet by --merge-union
 
 	for {
 		fields, err := r.Read()
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return err
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return fmt.Errorf("%v: %w", in.Name, err)
+			}
+			if strings.EqualFold(c.OnError, "fail") {
+				return fmt.Errorf("%v: line %d, column %d: %w", in.Name, perr.Line, perr.Column, perr.Err)
+			}
+
+			c.collectError(oc, rowError{
+				Input:  in.Name,
+				Line:   perr.Line,
+				Value:  lines.between(perr.StartLine, perr.Line),
+				Reason: fmt.Sprintf("%v (column %d)", perr.Err, perr.Column),
+			})
+
+			csvrindex++
+			continue
+		}
+		if lines != nil {
+			line, _ := r.FieldPos(0)
+			lines.forget(line)
 		}
 
 		if csvrindex == c.Header-1 {
 			for cindex := range fields {
 				columns = append(columns, strings.TrimSpace(fields[cindex]))
+				c.explainColumn(oc, sheet, columns[cindex], cindex)
 			}
 
-			err := writeXlsxHeader(oc.output, sheet, xlsxrindex, fields)
-			if err != nil {
-				return err
+			if st.header != nil && c.MergeInto == "" && !c.headerMatches(st, columns) {
+				return fmt.Errorf("%v: the header does not match that of %v", in.Name, st.xlsxsheet)
+			}
+
+			if st.header == nil {
+				header := fields
+				if offset > 0 {
+					header = append([]string{c.SourceColumn}, fields...)
+				}
+
+				err := c.writeHeader(st, header)
+				if err != nil {
+					return err
+				}
 			}
 
-			xlsxrindex++
+			if c.MergeUnion {
+				mapping = unionMapping(st.names, columns)
+			}
 		}
 		if csvrindex <= c.Header-1 {
 			csvrindex++
 			continue
 		}
 
+		// the rows of the sheet, including those above the origin
+		if st.row0+st.rindex >= excelize.TotalRows || c.Split && st.row0+st.rindex >= c.SplitRows {
+			if !c.Split {
+				return fmt.Errorf("%v: exceeds %d rows of a worksheet (see --split)", st.sheet, excelize.TotalRows)
+			}
+
+			err = c.rollover(oc, st)
+			if err != nil {
+				return err
+			}
+		}
+
 		for i := len(columns); i < len(fields); i++ {
 			colName, err := excelize.ColumnNumberToName(i + 1)
 			if err != nil {
@@ -257,14 +708,28 @@ func (c globalCmd) convertOne(oc outputContext, sheet string, input io.Reader) e
 			}
 
 			columns = append(columns, "$"+colName)
+			c.explainColumn(oc, sheet, columns[i], i)
 		}
 
+		width := len(fields)
+		if mapping != nil {
+			// fields beyond the header follow the union
+			width = len(st.names) + max(len(fields)-len(mapping), 0)
+		}
+		cells := make([]interface{}, offset+width)
+		if offset > 0 {
+			cells[0] = excelize.Cell{Value: in.Name}
+		}
+
+		rejected := false
 		for cindex, value := range fields {
 			colName := columns[cindex]
 
-			addr, err := excelize.CoordinatesToCellName(cindex+1, xlsxrindex+1)
-			if err != nil {
-				return fmt.Errorf("%v: %v\n", colName, err)
+			xcindex := offset + cindex
+			if cindex < len(mapping) {
+				xcindex = offset + mapping[cindex]
+			} else if mapping != nil {
+				xcindex = offset + len(st.names) + cindex - len(mapping)
 			}
 
 			if len(value) == 0 {
@@ -272,50 +737,101 @@ func (c globalCmd) convertOne(oc outputContext, sheet string, input io.Reader) e
 			}
 
 			if !c.GuessType {
-				err = oc.output.SetCellValue(sheet, addr, value)
-				if err != nil {
-					return err
-				}
-
+				cells[xcindex] = excelize.Cell{Value: value}
 				continue
 			}
 
+			// by the CSV name, so that SHEET! hints apply to continuation sheets too
 			hindex := oc.hints.findByName(sheet, colName, cindex+1)
 			col := column{}
 			if hindex != -1 {
 				col = oc.hints[hindex]
+			} else if cindex < len(in.Inferred) {
+				col.Type = in.Inferred[cindex]
 			}
 
 			typ, ival := c.guess(value, col)
+			if hindex != -1 && typ.baseType != col.Type.baseType {
+				if c.Strict && strings.EqualFold(c.OnError, "fail") {
+					addr, _ := st.w.cellName(xcindex, st.rindex)
+					return fmt.Errorf("%v!%v: %q does not match %v", st.xlsxsheet, addr, value, col.Type)
+				}
 
-			err = writeXlsx(oc.output, sheet, addr, typ, ival, oc.styles)
+				line, _ := r.FieldPos(cindex)
+				c.collectError(oc, rowError{
+					Input:    in.Name,
+					Line:     line,
+					Column:   colName,
+					Value:    value,
+					Expected: col.Type.String(),
+					Reason:   "not a " + string(col.Type.baseType),
+				})
+
+				if c.Strict {
+					rejected = true
+					break
+				}
+			}
+
+			cell, err := xlsxCell(oc.output, typ, ival, oc.styles)
+			if err != nil {
+				return fmt.Errorf("%v: %v", colName, err)
+			}
+			cells[xcindex] = cell
+		}
+		if rejected {
+			csvrindex++
+			continue
+		}
+
+		err = st.w.writeRow(st.rindex, cells)
+		if err != nil {
+			return err
+		}
+		if c.Autofit {
+			err = st.widths.addRow(st.w, st.rindex, cells)
 			if err != nil {
 				return err
 			}
 		}
 
-		xlsxrindex++
+		st.rindex++
 		csvrindex++
 	}
 
 	return nil
 }
 
-func writeXlsxHeader(f *excelize.File, sheet string, rindex int, fields []string) error {
+// explainColumn prints the declaration applied to the column for --explain-columns.
+func (c globalCmd) explainColumn(oc outputContext, sheet, colName string, cindex int) {
+	if oc.explain == nil || !c.GuessType {
+		return
+	}
+
+	if colName == "" {
+		colName = "#" + strconv.Itoa(cindex+1)
+	}
+
+	hindex := oc.hints.findByName(sheet, colName, cindex+1)
+	if hindex == -1 {
+		fmt.Fprintf(oc.explain, "%v!%v: no declaration\n", sheet, colName)
+		return
+	}
+
+	h := oc.hints[hindex]
+	fmt.Fprintf(oc.explain, "%v!%v: %v:%v\n", sheet, colName, h.Rule, h.Type)
+}
+
+func writeXlsxHeader(w *sheetWriter, rindex int, fields []string, styleID int) error {
+	cells := make([]interface{}, len(fields))
 	for cindex, value := range fields {
-		addr, err := excelize.CoordinatesToCellName(cindex+1, rindex+1)
-		if err != nil {
-			return err
-		}
-		err = f.SetCellValue(sheet, addr, value)
-		if err != nil {
-			return err
-		}
+		cells[cindex] = excelize.Cell{Value: value, StyleID: styleID}
 	}
-	return nil
+	return w.writeRow(rindex, cells)
 }
 
-func writeXlsx(f *excelize.File, sheet string, axis string, typ derivedType, value interface{}, styles map[string]int) error {
+// xlsxCell converts a typed value into a cell, defining its number format style on demand.
+func xlsxCell(f *excelize.File, typ derivedType, value interface{}, styles map[string]int) (excelize.Cell, error) {
 	outputfmt := typ.explicitOutputFormat
 	if outputfmt == "" {
 		outputfmt = typ.implicitOutputFormat
@@ -325,60 +841,37 @@ func writeXlsx(f *excelize.File, sheet string, axis string, typ derivedType, val
 		var err error
 		style, err = defineStyle(f, outputfmt)
 		if err != nil {
-			return err
+			return excelize.Cell{}, err
 		}
-
+		styles[outputfmt] = style
 	}
 
 	switch typ.baseType {
 	case typeText:
-		err := f.SetCellValue(sheet, axis, value)
-		if err != nil {
-			return err
-		}
+		return excelize.Cell{Value: value}, nil
 
-	case typeDatetime:
-		err := setCellValueAndStyle(f, sheet, axis, value, style)
-		if err != nil {
-			return err
-		}
-
-	case typeDate:
-		err := setCellValueAndStyle(f, sheet, axis, value, style)
-		if err != nil {
-			return err
-		}
+	case typeDatetime, typeDate, typeNumber, typePercent, typeCurrency, typeDuration:
+		return excelize.Cell{StyleID: style, Value: value}, nil
 
 	case typeTime:
 		tval := value.(time.Time)
 		if y, m, d := tval.Date(); y == 0 && m == 1 && d == 1 {
 			tval = time.Date(1900, 1, 1, tval.Hour(), tval.Minute(), tval.Second(), tval.Nanosecond(), tval.Location())
 		}
-		err := setCellValueAndStyle(f, sheet, axis, tval, style)
-		if err != nil {
-			return err
-		}
-
-	case typeNumber:
-		err := setCellValueAndStyle(f, sheet, axis, value, style)
-		if err != nil {
-			return err
-		}
+		return excelize.Cell{StyleID: style, Value: tval}, nil
 
 	case typeFormula:
-		err := setCellFormulaAndStyle(f, sheet, axis, value, style)
-		if err != nil {
-			return err
+		var fstr string
+		if s, ok := value.(string); ok {
+			fstr = s
+		} else if s, ok := value.(fmt.Stringer); ok {
+			fstr = s.String()
 		}
+		return excelize.Cell{StyleID: style, Formula: fstr}, nil
 
 	default:
-		err := f.SetCellValue(sheet, axis, value)
-		if err != nil {
-			return err
-		}
+		return excelize.Cell{Value: value}, nil
 	}
-
-	return nil
 }
 
 var longNumRE = regexp.MustCompile(`\s*(\d+)(?:\.(\d+))?\s*`)
@@ -401,25 +894,28 @@ func (c globalCmd) guess(value string, col column) (derivedType, interface{}) {
 		return typeBool.derive("", ""), false
 	}
 
+	_, in, out := c.zonesOf(derivedType{})
+
 	typetest := typeDatetime.derive("", "")
-	if t, ok := parseTime(value, typetest.implicitInputFormat); ok {
-		return typetest, t
+	if t, ok := parseTime(value, in, typetest.implicitInputFormat); ok {
+		return typetest, inZone(t, out)
 	}
 
 	typetest = typeDate.derive("", "")
 	ptns := translateDatePatterns(typetest.implicitInputFormat)
-	if t, ok := parseTime(value, ptns...); ok {
+	if t, ok := parseTime(value, in, ptns...); ok {
 		return typetest, t
 	}
 
 	typetest = typeTime.derive("", "")
 	ptns = translateTimePatterns(typetest.implicitInputFormat)
-	if t, ok := parseTime(value, ptns...); ok {
+	if t, ok := parseTime(value, nil, ptns...); ok {
 		return typetest, t
 	}
 
-	if f, err := strconv.ParseFloat(value, 64); err == nil {
-		if matches := longNumRE.FindStringSubmatch(value); len(matches) >= 2 {
+	num, format := localeNumber(value, c.numberLocaleOf(derivedType{}))
+	if f, err := strconv.ParseFloat(num, 64); err == nil {
+		if matches := longNumRE.FindStringSubmatch(num); len(matches) >= 2 {
 			if len(matches[1])+len(matches[2]) >= 16 {
 				return typeText.derive("", ""), value
 			}
@@ -431,7 +927,7 @@ func (c globalCmd) guess(value string, col column) (derivedType, interface{}) {
 				}
 			}
 		}
-		return typeNumber.derive("", ""), f
+		return typeNumber.derive("", format), f
 	}
 
 	return typeUnknown.derive("", ""), value
@@ -443,29 +939,60 @@ func (c globalCmd) guessByColType(value string, col column) (derivedType, interf
 		return col.Type, value
 
 	case typeNumber:
-		if f, err := strconv.ParseFloat(value, 64); err == nil {
+		num, format := localeNumber(value, c.numberLocaleOf(col.Type))
+		if f, err := strconv.ParseFloat(num, 64); err == nil {
+			return col.Type.withImplicitOutputFormat(format), f
+		}
+
+	case typePercent:
+		if f, ok := parsePercent(value, c.numberLocaleOf(col.Type)); ok {
 			return col.Type, f
 		}
 
+	case typeCurrency:
+		if f, format, ok := c.parseCurrency(value, col.Type); ok {
+			return col.Type.withImplicitOutputFormat(format), f
+		}
+
 	case typeDate:
-		ptns := translateDatePatterns(col.Type.explicitInputFormat)
+		layout, in, out := c.zonesOf(col.Type)
+		if t, ok := parseTimestamp(value, in, layout, col.Type.implicitInputFormat); ok {
+			y, m, d := inZone(t, out).Date()
+			return col.Type, time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		}
+		ptns := translateDatePatterns(layout)
 		ptns = append(ptns, translateDatePatterns(col.Type.implicitInputFormat)...)
-		if t, ok := parseTime(value, ptns...); ok {
-			return col.Type, t
+		if t, ok := parseTime(value, in, ptns...); ok {
+			t = inZone(t, out)
+			y, m, d := t.Date()
+			return col.Type, time.Date(y, m, d, 0, 0, 0, 0, t.Location())
 		}
 
 	case typeTime:
 		ptns := translateTimePatterns(col.Type.explicitInputFormat)
 		ptns = append(ptns, translateTimePatterns(col.Type.implicitInputFormat)...)
-		if t, ok := parseTime(value, ptns...); ok {
+		if t, ok := parseTime(value, nil, ptns...); ok {
 			return col.Type, t
 		}
 
 	case typeDatetime:
-		ptns := append([]string{}, col.Type.explicitInputFormat)
+		layout, in, out := c.zonesOf(col.Type)
+		if t, ok := parseTimestamp(value, in, layout, col.Type.implicitInputFormat); ok {
+			return col.Type, inZone(t, out)
+		}
+		ptns := append([]string{}, layout)
 		ptns = append(ptns, col.Type.implicitInputFormat)
-		if t, ok := parseTime(value, ptns...); ok {
-			return col.Type, t
+		if t, ok := parseTime(value, in, ptns...); ok {
+			return col.Type, inZone(t, out)
+		}
+
+	case typeDuration:
+		unit := col.Type.explicitInputFormat
+		if unit == "" {
+			unit = "s"
+		}
+		if d, ok := parseDuration(value, unit); ok {
+			return col.Type, d
 		}
 
 	case typeBool:
@@ -486,13 +1013,18 @@ func (c globalCmd) guessByColType(value string, col column) (derivedType, interf
 	return typeUnknown.derive("", ""), value
 }
 
-func parseTime(value string, layouts ...string) (time.Time, bool) {
+// parseTime parses the value by the first matching layout, in the location of values without time zones (UTC for nil).
+func parseTime(value string, loc *time.Location, layouts ...string) (time.Time, bool) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
 	for i := range layouts {
-		if len(layouts[i]) != len(value) {
+		if len(layouts[i]) != len(value) && !hasZone(layouts[i]) {
 			continue
 		}
 
-		if t, err := time.Parse(layouts[i], value); err == nil {
+		if t, err := time.ParseInLocation(layouts[i], value, loc); err == nil {
 			return t, true
 		}
 	}
@@ -590,6 +1122,18 @@ func defineStyle(f *excelize.File, s string) (int, error) {
 	return f.NewStyle(&excelize.Style{CustomNumFmt: &s})
 }
 
+func setCell(f *excelize.File, sheet, axis string, cell excelize.Cell) error {
+	if cell.Formula != "" {
+		return setCellFormulaAndStyle(f, sheet, axis, cell.Formula, cell.StyleID)
+	}
+
+	if cell.StyleID == 0 {
+		return f.SetCellValue(sheet, axis, cell.Value)
+	}
+
+	return setCellValueAndStyle(f, sheet, axis, cell.Value, cell.StyleID)
+}
+
 func setCellValueAndStyle(f *excelize.File, sheet, axis string, value interface{}, styleID int) error {
 	err := f.SetCellValue(sheet, axis, value)
 	if err != nil {
@@ -604,15 +1148,8 @@ func setCellValueAndStyle(f *excelize.File, sheet, axis string, value interface{
 	return nil
 }
 
-func setCellFormulaAndStyle(f *excelize.File, sheet, axis string, formula interface{}, styleID int) error {
-	var fstr string
-	if s, ok := formula.(string); ok {
-		fstr = s
-	} else if s, ok := formula.(fmt.Stringer); ok {
-		fstr = s.String()
-	}
-
-	err := f.SetCellFormula(sheet, axis, fstr)
+func setCellFormulaAndStyle(f *excelize.File, sheet, axis, formula string, styleID int) error {
+	err := f.SetCellFormula(sheet, axis, formula)
 	if err != nil {
 		return err
 	}
@@ -631,23 +1168,63 @@ func fileExists(name string) bool {
 }
 
 func main() {
-	app := gli.NewWith(&globalCmd{})
+	app := gli.NewWith(&globalCmd{cliArgs: os.Args[1:]})
 	app.Name = "csv2xlsx"
 	app.Desc = "CSV to XLSX file converter"
 	app.Version = Version
 	app.Usage = `csv2xlsx [options] -o FILENAME CSV_FILENAME [CSV_FILENAME...]
 
 --columns [SHEET!]COLUMN_NAME:TYPE[(INPUT_FORMAT[->OUTPUT_FORMAT])]
-  SHEET = CSV_FILENAME
-  TYPE = text | number | date | time | datetime | bool | formula
+  SHEET = the sheet name (CSV_FILENAME by --sheet-name, or the sheet of --into or --merge-into)
+  COLUMN_NAME, SHEET = a name, a wildcard (num_*) or a regular expression (re:^amt_\d+$)
+    exact names win over patterns, and then the first declaration wins
+  TYPE = text | number | percent | currency | date | time | datetime | duration | bool | formula
   INPUT_FORMAT
     date: yyyy, yy, y, 2006, 06, mm, m, 01, 1, dd, d, 02, 2
     time: hh, h, 15, 3, mm, m, 04, 4, ss, s, 05, 5
     datetime: 2006, 06, 01, 1, 02, 2, 15, 3, 04, 4, 05, 5
+    date, datetime: epoch, epoch_ms, epoch_us (Unix time) or excel_serial as a whole,
+      followed by tz:ZONE (tz=ZONE only in --config), the time zone of values without time zones
+    number, percent: a locale (de-DE or locale:de-DE) of --number-locale
+    currency: a currency code (JPY, USD, EUR, ...) for its format, or none to keep symbols as written
+    duration: s or ms of plain numbers (default: s), besides h:mm:ss and ISO 8601 (PT1H30M)
   Examples:
     csv2xlsx -o dest.xlsx src.csv
     csv2xlsx -o dest.xlsx --columns num_*:number src.csv
     csv2xlsx -o dest.xlsx --columns num_*:"number(->#\,##0.00)" src.csv
+    csv2xlsx -o dest.xlsx --columns "re:^amt_\d+$:number" --explain-columns src.csv
+    csv2xlsx -o dest.xlsx --number-locale en-US --columns "eur_*:number(de-DE)" src.csv
+    csv2xlsx -o dest.xlsx --columns "rate:percent,price:currency(JPY)" src.csv
+    csv2xlsx -o dest.xlsx --columns "elapsed:duration(ms->[m]:ss.000)" src.csv
+    csv2xlsx -o dest.xlsx --columns "ts:datetime(epoch_ms)" src.csv
+    csv2xlsx -o dest.xlsx --tz Asia/Tokyo --columns "at:datetime(2006-01-02T15:04:05Z07:00)" src.csv
+    csv2xlsx -o dest.xlsx --tz Asia/Tokyo --columns "at:datetime(2006/01/02 15:04 tz:UTC)" src.csv
+    csv2xlsx -o dest.xlsx --table --table-style TableStyleLight9 src.csv
+    csv2xlsx -o dest.xlsx --header-style bold+fill:DDEBF7 --freeze 1 --autofilter on src.csv
+    csv2xlsx -o dest.xlsx --autofit --autofit-max 40 src.csv
+    csv2xlsx -o dest.xlsx --sheet-name "{dir}_{base}" data/*/q1.csv
+    csv2xlsx -o log.xlsx --append today.csv
+    csv2xlsx -o dest.xlsx --start-cell "report.csv!C5" report.csv
+    csv2xlsx -o report.xlsx --template template.xlsx --into "sales.csv->Data!B3" --table sales.csv
+    csv2xlsx -o dest.xlsx --merge-into sales --merge-union --source-column file sales_*.csv
+    csv2xlsx schema src.csv
+    csv2xlsx xlsx2csv --dir out --date yyyy-mm-dd dest.xlsx
+    csv2xlsx -o dest.xlsx --config rules.yaml src.csv
+
+--config FILE (.yaml, .yml, .json or .toml)
+  date-xlsx: yyyy-mm-dd        # global options by long names or field names
+  columns:                     # the same as "csv2xlsx schema --format yaml"
+    - sheet: src.csv           # optional
+      column: when
+      type: date
+      input: Jan 2, 2006       # optional
+      output: yyyy/mm/dd       # optional
+  sheets:                      # the same as --header-style, --freeze, --autofilter and --start-cell
+    - sheet: src.csv           # optional
+      header-style: bold+fill=DDEBF7
+      freeze: 1
+      autofilter: on
+      start-cell: B3
 `
 	app.Copyright = "(C) 2022 Shuhei Kubota"
 	err := app.Run(os.Args)
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// merge writes all inputs into the sheet of --merge-into.
+// Headers are read ahead, so that they are checked (or merged with --merge-union) before any rows.
+func (c globalCmd) merge(oc outputContext, t sheetTarget) error {
+	st, err := c.beginSheet(oc, t.sheet, t.cell)
+	if err != nil {
+		return err
+	}
+
+	var inputs []input
+	var names []string
+	first := ""
+	if st.header != nil {
+		// by --append
+		names = append(names, st.names...)
+		first = st.xlsxsheet
+	}
+
+	for _, in := range oc.inputs {
+		in, cleanup, err := c.openInput(in)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+
+		var header []string
+		if c.Header > 0 {
+			in, header, err = c.peekHeader(in)
+			if err != nil {
+				return err
+			}
+		}
+		inputs = append(inputs, in)
+
+		if header == nil {
+			// empty
+			continue
+		}
+
+		columns := make([]string, len(header))
+		for i := range header {
+			columns[i] = strings.TrimSpace(header[i])
+		}
+
+		switch {
+		case names == nil:
+			names = columns
+			first = in.Name
+
+		case c.MergeUnion:
+			names = unionNames(names, columns)
+
+		case !sameNames(names, columns):
+			return fmt.Errorf("%v: the header does not match that of %v (see --merge-union)", in.Name, first)
+		}
+	}
+
+	if names != nil {
+		header := names
+		if c.SourceColumn != "" {
+			header = append([]string{c.SourceColumn}, names...)
+		}
+
+		switch {
+		case st.header == nil:
+			err := c.writeHeader(st, header)
+			if err != nil {
+				return err
+			}
+
+		case len(names) > len(st.names):
+			// columns added to the sheet by --merge-union
+			rindex := st.rindex
+			st.rindex = 0
+			err := c.writeHeader(st, header)
+			if err != nil {
+				return err
+			}
+			st.rindex = rindex
+		}
+		st.names = names
+	}
+
+	for _, in := range inputs {
+		err := c.convertOne(oc, in, st)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.closeSheet(oc, st)
+}
+
+// peekHeader reads the header of the input, and returns the input rewound.
+func (c globalCmd) peekHeader(in input) (input, []string, error) {
+	spool := &bytes.Buffer{}
+	rest := in.Reader
+	in.Reader = io.TeeReader(rest, spool)
+
+	r := c.newCSVReader(in)
+	r.FieldsPerRecord = -1
+
+	var header []string
+	for csvrindex := 0; csvrindex <= c.Header-1; csvrindex++ {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				// reported by the conversion
+				continue
+			}
+			return input{}, nil, err
+		}
+
+		if csvrindex == c.Header-1 {
+			header = fields
+		}
+	}
+
+	in.Reader = io.MultiReader(spool, rest)
+
+	return in, header, nil
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(strings.TrimSpace(a[i]), strings.TrimSpace(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// unionNames appends names not in names.
+// Duplicated names are matched in order, so that a CSV with two "memo" columns adds the second.
+func unionNames(names, columns []string) []string {
+	mapping := unionMapping(names, columns)
+	for i, m := range mapping {
+		if m == -1 {
+			mapping[i] = len(names)
+			names = append(names, columns[i])
+		}
+	}
+	return names
+}
+
+// unionMapping returns indexes of columns in names, or -1.
+func unionMapping(names, columns []string) []int {
+	mapping := make([]int, len(columns))
+	used := make([]bool, len(names))
+	for i, col := range columns {
+		mapping[i] = -1
+		for j, name := range names {
+			if !used[j] && strings.EqualFold(strings.TrimSpace(name), col) {
+				mapping[i] = j
+				used[j] = true
+				break
+			}
+		}
+	}
+	return mapping
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+// numberLocale is how numbers are written in a locale.
+type numberLocale struct {
+	decimal rune
+	groups  string // separators of digit groups
+	indian  bool   // grouped by 2 digits above thousands, like 12,34,567
+
+	re *regexp.Regexp
+}
+
+const (
+	dotDecimalLanguages   = "en ja zh ko th he ms hi fil sw ga mt"
+	commaDecimalLanguages = "de fr es it pt nl ru uk pl cs sk sv da nb nn no fi tr el hu ro bg hr sl sr lt lv et id vi ca eu gl be kk az is af"
+
+	spaceGroups = " \u00a0\u202f"
+)
+
+// regionNumberLocales are regions writing numbers unlike their languages.
+var regionNumberLocales = map[string]numberLocale{
+	"de-CH": {decimal: '.', groups: "'’" + spaceGroups},
+	"de-LI": {decimal: '.', groups: "'’" + spaceGroups},
+	"fr-CH": {decimal: '.', groups: "'’" + spaceGroups},
+	"it-CH": {decimal: '.', groups: "'’" + spaceGroups},
+	"es-MX": {decimal: '.', groups: "," + spaceGroups},
+	"es-US": {decimal: '.', groups: "," + spaceGroups},
+	"en-IN": {decimal: '.', groups: ",", indian: true},
+	"en-ZA": {decimal: ',', groups: spaceGroups},
+}
+
+var numberLocaleCache = make(map[string]*numberLocale)
+
+// lookupNumberLocale returns how numbers are written in the locale, like de-DE.
+func lookupNumberLocale(name string) (*numberLocale, error) {
+	if loc, found := numberLocaleCache[name]; found {
+		return loc, nil
+	}
+
+	tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown number locale %q", name)
+	}
+	base, _ := tag.Base()
+	region, _ := tag.Region()
+
+	loc, found := regionNumberLocales[base.String()+"-"+region.String()]
+	if !found {
+		switch {
+		case strings.Contains(" "+dotDecimalLanguages+" ", " "+base.String()+" "):
+			loc = numberLocale{decimal: '.', groups: "," + spaceGroups, indian: base.String() == "hi"}
+		case strings.Contains(" "+commaDecimalLanguages+" ", " "+base.String()+" "):
+			loc = numberLocale{decimal: ',', groups: "." + spaceGroups}
+		default:
+			return nil, fmt.Errorf("unknown number locale %q", name)
+		}
+	}
+
+	g := "[" + regexp.QuoteMeta(loc.groups) + "]"
+	integer := `\d{1,3}(?:` + g + `\d{3})*`
+	if loc.indian {
+		integer = `\d{1,2}(?:` + g + `\d{2})*` + g + `\d{3}|\d{1,3}`
+	}
+	d := regexp.QuoteMeta(string(loc.decimal))
+	loc.re = regexp.MustCompile(`^(?:(` + integer + `|\d+)(?:` + d + `(\d+))?|` + d + `(\d+))$`)
+
+	numberLocaleCache[name] = &loc
+
+	return &loc, nil
+}
+
+// numberLocaleOf returns the locale of the number type, by its input format (de-DE or locale=de-DE), or --number-locale.
+func (c globalCmd) numberLocaleOf(t derivedType) *numberLocale {
+	name := t.explicitInputFormat
+	if name == "" {
+		name = c.NumberLocale
+	}
+	if name == "" {
+		return nil
+	}
+
+	loc, err := lookupNumberLocale(localeName(name))
+	if err != nil {
+		// checked by parseHints
+		return nil
+	}
+	return loc
+}
+
+// localeName strips locale= (or locale:) of an input format.
+func localeName(format string) string {
+	if key, value, found := strings.Cut(format, "="); found && strings.EqualFold(strings.TrimSpace(key), "locale") {
+		return strings.TrimSpace(value)
+	}
+	if key, value, found := strings.Cut(format, ":"); found && strings.EqualFold(strings.TrimSpace(key), "locale") {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(format)
+}
+
+// currencyCodes are ISO 4217 codes written with amounts, not any three letters like SKU of product codes.
+const currencyCodes = `USD|EUR|JPY|GBP|CHF|CNY|KRW|INR|AUD|CAD|NZD|HKD|SGD|TWD|THB|IDR|MYR|PHP|VND|SEK|NOK|DKK|ISK|PLN|CZK|HUF|RON|BGN|RUB|UAH|TRY|ILS|AED|SAR|BRL|MXN|ARS|CLP|COP|PEN|ZAR`
+
+const currencySymbols = `[A-Z]{1,3}\p{Sc}|\p{Sc}|` + currencyCodes + `|円|元|kr\.?|zł|Kč|Ft`
+
+var currencyRE = regexp.MustCompile(`^(?:` + currencySymbols + `)`)
+var currencySuffixRE = regexp.MustCompile(`(?:` + currencySymbols + `)$`)
+
+// parseLocaleNumber parses a number written in the locale, with digit groups, a currency symbol,
+// a sign (a trailing minus too) or accounting parentheses.
+// It returns the number for strconv.ParseFloat, the number format to display it in the same way,
+// which is empty for a plain number, and the currency symbol (or code) written, if any.
+func parseLocaleNumber(value string, loc *numberLocale) (string, string, string, bool) {
+	s := strings.TrimSpace(value)
+
+	parens := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		parens = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	var prefix, suffix string // the currency, with a space between the number
+	mark := ""
+	sign, trailingMinus := "", false
+strip:
+	for {
+		switch {
+		case prefix == "" && suffix == "" && currencyRE.MatchString(s):
+			m := currencyRE.FindString(s)
+			s = s[len(m):]
+			mark = m
+			prefix = m + leadingSpace(s)
+			s = strings.TrimSpace(s)
+		case prefix == "" && suffix == "" && currencySuffixRE.MatchString(s):
+			m := currencySuffixRE.FindString(s)
+			s = s[:len(s)-len(m)]
+			mark = m
+			suffix = trailingSpace(s) + m
+			s = strings.TrimSpace(s)
+		case sign == "" && (strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+")):
+			sign = s[:1]
+			s = strings.TrimSpace(s[1:])
+		case sign == "" && strings.HasPrefix(s, "−"):
+			sign = "-"
+			s = strings.TrimSpace(strings.TrimPrefix(s, "−"))
+		case sign == "" && strings.HasSuffix(s, "-"):
+			sign, trailingMinus = "-", true
+			s = strings.TrimSpace(s[:len(s)-1])
+		default:
+			break strip
+		}
+	}
+
+	if parens && sign != "" {
+		return "", "", "", false
+	}
+
+	m := loc.re.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", "", false
+	}
+	integer, fraction := m[1], m[2]
+	if integer == "" {
+		integer, fraction = "0", m[3]
+	}
+	if len(integer) > 1 && integer[0] == '0' {
+		// a code like 0123, not a number
+		return "", "", "", false
+	}
+	grouped := strings.ContainsAny(integer, loc.groups)
+	for _, g := range loc.groups {
+		integer = strings.ReplaceAll(integer, string(g), "")
+	}
+
+	num := integer
+	if fraction != "" {
+		num += "." + fraction
+	}
+	if parens || sign == "-" {
+		num = "-" + num
+	}
+
+	if !grouped && prefix == "" && suffix == "" && !parens && !trailingMinus {
+		return num, "", "", true
+	}
+
+	format := "0"
+	if grouped || prefix != "" || suffix != "" {
+		format = "#,##0"
+	}
+	if fraction != "" {
+		format += "." + strings.Repeat("0", len(fraction))
+	}
+	if prefix != "" {
+		format = `"` + prefix + `"` + format
+	}
+	if suffix != "" {
+		format += `"` + suffix + `"`
+	}
+	switch {
+	case parens:
+		format += ";(" + format + ")"
+	case trailingMinus:
+		format += ";" + format + "-"
+	}
+
+	return num, format, mark, true
+}
+
+func leadingSpace(s string) string {
+	if s != strings.TrimLeftFunc(s, unicode.IsSpace) {
+		return " "
+	}
+	return ""
+}
+
+func trailingSpace(s string) string {
+	if s != strings.TrimRightFunc(s, unicode.IsSpace) {
+		return " "
+	}
+	return ""
+}
+
+// localeNumber returns the value for strconv.ParseFloat, parsed as written in the locale (if any),
+// and the number format to display it in the same way, which is empty for a plain number.
+func localeNumber(value string, loc *numberLocale) (string, string) {
+	if loc != nil {
+		if num, format, _, ok := parseLocaleNumber(value, loc); ok {
+			return num, format
+		}
+	}
+	return value, ""
+}
+
+// parsePercent parses 12.5% (or 12.5 ％) as 0.125, and a number without % as a fraction.
+func parsePercent(value string, loc *numberLocale) (float64, bool) {
+	s := strings.TrimSpace(value)
+
+	percent := false
+	for _, sign := range []string{"%", "％"} {
+		if t, found := strings.CutSuffix(s, sign); found {
+			s, percent = strings.TrimSpace(t), true
+			break
+		}
+	}
+
+	num, _ := localeNumber(s, loc)
+	if percent {
+		// shifted by the exponent, for 33.3% to be exactly 0.333
+		num += "e-2"
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// currency is how amounts of a currency are written and displayed.
+type currency struct {
+	code     string
+	symbol   string
+	suffix   bool // the symbol follows the amount
+	decimals int
+	locale   string // how amounts are written, unless --number-locale
+}
+
+var currencies = map[string]currency{
+	"USD": {symbol: "$", decimals: 2, locale: "en-US"},
+	"CAD": {symbol: "$", decimals: 2, locale: "en-CA"},
+	"AUD": {symbol: "$", decimals: 2, locale: "en-AU"},
+	"EUR": {symbol: " €", suffix: true, decimals: 2, locale: "de-DE"},
+	"GBP": {symbol: "£", decimals: 2, locale: "en-GB"},
+	"CHF": {symbol: "CHF ", decimals: 2, locale: "de-CH"},
+	"JPY": {symbol: "¥", decimals: 0, locale: "ja-JP"},
+	"CNY": {symbol: "¥", decimals: 2, locale: "zh-CN"},
+	"KRW": {symbol: "₩", decimals: 0, locale: "ko-KR"},
+	"INR": {symbol: "₹", decimals: 2, locale: "en-IN"},
+}
+
+// currencyMarks are symbols written with amounts of currencies, besides their codes.
+var currencyMarks = map[string][]string{
+	"USD": {"$", "US$"},
+	"CAD": {"$", "CA$", "C$"},
+	"AUD": {"$", "A$", "AU$"},
+	"NZD": {"$", "NZ$"},
+	"HKD": {"$", "HK$"},
+	"SGD": {"$", "S$"},
+	"TWD": {"$", "NT$"},
+	"MXN": {"$", "MX$"},
+	"ARS": {"$"},
+	"CLP": {"$"},
+	"COP": {"$"},
+	"BRL": {"R$"},
+	"EUR": {"€"},
+	"GBP": {"£"},
+	"JPY": {"¥", "￥", "円"},
+	"CNY": {"¥", "￥", "CN¥", "元"},
+	"KRW": {"₩"},
+	"INR": {"₹"},
+	"RUB": {"₽"},
+	"UAH": {"₴"},
+	"TRY": {"₺"},
+	"ILS": {"₪"},
+	"VND": {"₫"},
+	"PHP": {"₱"},
+	"THB": {"฿"},
+	"SEK": {"kr", "kr."},
+	"NOK": {"kr", "kr."},
+	"DKK": {"kr", "kr."},
+	"ISK": {"kr", "kr."},
+	"PLN": {"zł"},
+	"CZK": {"Kč"},
+	"HUF": {"Ft"},
+}
+
+var currencyCodeRE = regexp.MustCompile(`^[A-Za-z]{3}$`)
+
+// lookupCurrency returns the currency of the code, like JPY.
+// Other codes are displayed as they are, like "SEK "#,##0.00.
+func lookupCurrency(code string) (currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !currencyCodeRE.MatchString(code) {
+		return currency{}, fmt.Errorf("%q is not a currency code like JPY or USD", code)
+	}
+
+	cur, found := currencies[code]
+	if !found {
+		cur = currency{symbol: code + " ", decimals: 2, locale: "en"}
+	}
+	cur.code = code
+	return cur, nil
+}
+
+// writtenWith reports whether amounts of the currency are written with the symbol (or code), or without any.
+func (cur currency) writtenWith(mark string) bool {
+	if mark == "" || strings.EqualFold(mark, cur.code) {
+		return true
+	}
+	for _, m := range currencyMarks[cur.code] {
+		if strings.EqualFold(mark, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// format returns the number format of the currency, like "$"#,##0.00.
+func (cur currency) format() string {
+	format := "#,##0"
+	if cur.decimals > 0 {
+		format += "." + strings.Repeat("0", cur.decimals)
+	}
+	if cur.suffix {
+		return format + `"` + cur.symbol + `"`
+	}
+	return `"` + cur.symbol + `"` + format
+}
+
+// parseCurrency parses an amount of the currency type, stripping currency symbols.
+// With a code (currency(JPY)), symbols of other currencies are not stripped but fail,
+// and amounts are written as in the country of the currency, or with a decimal point like 12.5 (unless --number-locale).
+// With no code (currency), the amount is displayed as written, like number with --number-locale.
+func (c globalCmd) parseCurrency(value string, t derivedType) (float64, string, bool) {
+	loc := c.numberLocaleOf(derivedType{})
+	locs := []*numberLocale{loc}
+
+	var cur *currency
+	format := ""
+	if t.explicitInputFormat != "" {
+		code, err := lookupCurrency(t.explicitInputFormat)
+		if err != nil {
+			// checked by parseHints
+			return 0, "", false
+		}
+		cur = &code
+		if loc == nil {
+			written, _ := lookupNumberLocale(cur.locale)
+			plain, _ := lookupNumberLocale("en")
+			locs = []*numberLocale{written, plain}
+		}
+		format = cur.format()
+	} else if loc == nil {
+		locs[0], _ = lookupNumberLocale("en")
+	}
+
+	for _, loc := range locs {
+		num, written, mark, ok := parseLocaleNumber(value, loc)
+		if !ok {
+			continue
+		}
+		if cur != nil && !cur.writtenWith(mark) {
+			return 0, "", false
+		}
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			continue
+		}
+
+		if format == "" {
+			format = written
+		}
+		return f, format, true
+	}
+
+	return 0, "", false
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andrew-d/go-termutil"
+	"gopkg.in/yaml.v3"
+)
+
+type schemaCmd struct {
+	Format string `cli:"format,f=text|json|yaml" default:"text" type:"Choice" choices:"text,json,yaml" help:"text prints --columns declarations, json and yaml print column rules"`
+}
+
+// columnRule is a --columns declaration in a structured form.
+type columnRule struct {
+	Sheet  string `json:"sheet,omitempty" yaml:"sheet,omitempty" toml:"sheet,omitempty"`
+	Column string `json:"column" yaml:"column" toml:"column"`
+	Type   string `json:"type" yaml:"type" toml:"type"`
+	Input  string `json:"input,omitempty" yaml:"input,omitempty" toml:"input,omitempty"`
+	Output string `json:"output,omitempty" yaml:"output,omitempty" toml:"output,omitempty"`
+}
+
+func (s schemaCmd) Before(args []string) error {
+	if termutil.Isatty(os.Stdin.Fd()) && len(args) == 0 {
+		return errors.New("at least one csv file is required")
+	}
+
+	return nil
+}
+
+func (s schemaCmd) Run(g *globalCmd, args []string) error {
+	var inputs []input
+
+	if !termutil.Isatty(os.Stdin.Fd()) {
+		inputs = append(inputs, input{
+			Name:   g.PipelinedName,
+			Reader: os.Stdin,
+		})
+	}
+
+	for _, csvfilename := range args {
+		csvfilename = filepath.Clean(csvfilename)
+
+		f, err := os.Open(csvfilename)
+		if err != nil {
+			return err
+		}
+		defer func(f *os.File) { f.Close() }(f)
+
+		inputs = append(inputs, input{
+			Name:   csvfilename,
+			Reader: f,
+		})
+	}
+
+	return s.print(os.Stdout, *g, inputs)
+}
+
+func (s schemaCmd) print(w io.Writer, c globalCmd, inputs []input) error {
+	hints, err := c.parseHints()
+	if err != nil {
+		return err
+	}
+
+	var rules []columnRule
+	names := c.sheetNames(inputs)
+	for i, in := range inputs {
+		in, cleanup, err := c.openInput(in)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+
+		header, tallies, err := c.sampleColumns(c.newCSVReader(in))
+		if err != nil {
+			return err
+		}
+
+		if strings.EqualFold(s.Format, "text") {
+			fmt.Fprintf(w, "# %v\n", names[i])
+		}
+
+		seen := make(map[string]bool)
+		for cindex := range tallies {
+			colName := "#" + strconv.Itoa(cindex+1)
+			if cindex < len(header) {
+				name := strings.TrimSpace(header[cindex])
+				// the name must be a key of --columns
+				if name != "" && !strings.ContainsAny(name, ":!") && !seen[strings.ToLower(name)] {
+					colName = name
+				}
+				seen[strings.ToLower(name)] = true
+			}
+
+			typ := tallies[cindex].infer()
+			if hindex := hints.findByName(names[i], colName, cindex+1); hindex != -1 {
+				typ = hints[hindex].Type
+			}
+			if typ.baseType == typeUnknown {
+				continue
+			}
+			if (typ.baseType == typeNumber || typ.baseType == typePercent) && typ.explicitInputFormat == "" && c.NumberLocale != "" {
+				// to be reused without --number-locale
+				typ.explicitInputFormat = c.NumberLocale
+			}
+
+			if strings.EqualFold(s.Format, "text") {
+				spec := names[i] + "!" + colName + ":" + typ.spec()
+				fmt.Fprintln(w, strings.ReplaceAll(spec, ",", `\,`))
+				continue
+			}
+
+			rule := columnRule{
+				Sheet:  names[i],
+				Column: colName,
+				Type:   string(typ.baseType),
+				Input:  typ.explicitInputFormat,
+				Output: typ.explicitOutputFormat,
+			}
+			if rule.Input == "" {
+				rule.Input = typ.implicitInputFormat
+			}
+			if rule.Output == "" {
+				rule.Output = typ.implicitOutputFormat
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	// the same form as --config
+	doc := configFile{Columns: rules}
+
+	switch strings.ToLower(s.Format) {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		return enc.Encode(doc)
+	}
+
+	return nil
+}
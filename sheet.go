@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetWriter writes rows into a worksheet.
+// With a StreamWriter, rows must be written in ascending order and flushed at the end.
+type sheetWriter struct {
+	f      *excelize.File
+	sheet  string
+	stream *excelize.StreamWriter
+
+	// the origin of rows and columns, 0 for A1
+	row0, col0 int
+}
+
+func newSheetWriter(f *excelize.File, sheet string, stream bool) (*sheetWriter, error) {
+	w := &sheetWriter{
+		f:     f,
+		sheet: sheet,
+	}
+
+	if stream {
+		sw, err := f.NewStreamWriter(sheet)
+		if err != nil {
+			return nil, err
+		}
+		w.stream = sw
+	}
+
+	return w, nil
+}
+
+// cellName is the name of the cell at cindex and rindex from the origin.
+func (w *sheetWriter) cellName(cindex, rindex int) (string, error) {
+	return excelize.CoordinatesToCellName(w.col0+cindex+1, w.row0+rindex+1)
+}
+
+// rangeName is the range of rows and cols from the origin.
+func (w *sheetWriter) rangeName(rows, cols int) (string, error) {
+	topLeft, err := w.cellName(0, 0)
+	if err != nil {
+		return "", err
+	}
+	bottomRight, err := w.cellName(cols-1, rows-1)
+	if err != nil {
+		return "", err
+	}
+	return topLeft + ":" + bottomRight, nil
+}
+
+// writeRow writes excelize.Cell values from the origin column. nil cells are left empty.
+func (w *sheetWriter) writeRow(rindex int, cells []interface{}) error {
+	if w.stream != nil {
+		addr, err := w.cellName(0, rindex)
+		if err != nil {
+			return err
+		}
+		return w.stream.SetRow(addr, cells)
+	}
+
+	for cindex, cell := range cells {
+		if cell == nil {
+			continue
+		}
+
+		addr, err := w.cellName(cindex, rindex)
+		if err != nil {
+			return err
+		}
+
+		err = setCell(w.f, w.sheet, addr, cell.(excelize.Cell))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *sheetWriter) flush() error {
+	if w.stream == nil {
+		return nil
+	}
+	return w.stream.Flush()
+}
+
+// addTable adds a table. With a StreamWriter, it must be called before flush.
+func (w *sheetWriter) addTable(table *excelize.Table) error {
+	if w.stream != nil {
+		return w.stream.AddTable(table)
+	}
+	return w.f.AddTable(w.sheet, table)
+}
+
+// setPanes freezes panes. With a StreamWriter, it must be called before writeRow.
+func (w *sheetWriter) setPanes(panes *excelize.Panes) error {
+	if w.stream != nil {
+		return w.stream.SetPanes(panes)
+	}
+	return w.f.SetPanes(w.sheet, panes)
+}
+
+// autoFilter puts a filter on the range, which is not supported with a StreamWriter.
+func (w *sheetWriter) autoFilter(rng string) error {
+	if w.stream != nil {
+		return errors.New("an autofilter is not supported with a StreamWriter")
+	}
+	return w.f.AutoFilter(w.sheet, rng, nil)
+}
+
+// setColWidth sets the width of the column at cindex from the origin. With a StreamWriter, it must be called before writeRow.
+func (w *sheetWriter) setColWidth(cindex int, width float64) error {
+	if w.stream != nil {
+		return w.stream.SetColWidth(w.col0+cindex+1, w.col0+cindex+1, width)
+	}
+
+	col, err := excelize.ColumnNumberToName(w.col0 + cindex + 1)
+	if err != nil {
+		return err
+	}
+	return w.f.SetColWidth(w.sheet, col, col, width)
+}
+
+// sheetState is a sheet being written, which continues over inputs with --merge-into
+// and rolls over to continuation sheets with --split.
+type sheetState struct {
+	sheet     string // the name of the first sheet
+	xlsxsheet string // the name of the sheet being written
+	part      int
+
+	layout sheetLayout
+	w      *sheetWriter
+	rindex int // the next row from the origin
+
+	// the origin of the first sheet, 0 for A1
+	row0, col0 int
+
+	// table is the table replaced by --table, or nil
+	table *excelize.Table
+
+	header []string // the header written, or nil
+	names  []string // the column names merged by --merge-union (without --source-column)
+
+	widths columnWidths
+}
+
+func (c globalCmd) beginSheet(oc outputContext, sheet, cell string) (*sheetState, error) {
+	layout, err := c.layoutOf(oc, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &sheetState{
+		sheet:     sheet,
+		xlsxsheet: sheet,
+		part:      1,
+		layout:    layout,
+	}
+
+	if cell != "" {
+		col, row, err := excelize.CellNameToCoordinates(cell)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", sheet, err)
+		}
+		st.row0, st.col0 = row-1, col-1
+	}
+
+	if c.Append {
+		err := c.resumeSheet(oc, st)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Table {
+		err := c.takeTable(oc, st)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !c.Append && !c.Stream && c.keepsSheet(sheetTarget{sheet: sheet, cell: cell}) {
+		err := c.clearSheet(oc, st)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	st.w, err = c.openSheet(oc, st)
+	if err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+// writeHeader writes the header at the current row.
+func (c globalCmd) writeHeader(st *sheetState, header []string) error {
+	if c.Table {
+		header = tableHeader(header)
+	}
+
+	err := writeXlsxHeader(st.w, st.rindex, header, st.layout.headerStyle)
+	if err != nil {
+		return err
+	}
+	if c.Autofit {
+		st.widths.addHeader(header, c.Table || st.layout.autofilter)
+	}
+
+	st.header = header
+	st.rindex++
+
+	return nil
+}
+
+// rollover closes the sheet and continues to the next sheet, 'SHEET (N)', with the same header.
+func (c globalCmd) rollover(oc outputContext, st *sheetState) error {
+	err := c.closeSheet(oc, st)
+	if err != nil {
+		return err
+	}
+
+	st.part++
+	st.xlsxsheet = splitSheetName(st.sheet, st.part)
+	_, err = oc.output.NewSheet(st.xlsxsheet)
+	if err != nil {
+		return err
+	}
+
+	// a new sheet, without what is around the origin of the first sheet
+	st.row0, st.col0 = 0, 0
+	st.w, err = c.openSheet(oc, st)
+	if err != nil {
+		return err
+	}
+
+	st.rindex = 0
+	st.widths = columnWidths{}
+	if st.header != nil {
+		return c.writeHeader(st, st.header)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	invalidSheetNameCharRE = regexp.MustCompile(`[\[\]:*?/\\]`)
+	sheetNamePlaceholderRE = regexp.MustCompile(`\{(\w*)\}`)
+)
+
+var sheetNamePlaceholders = []string{"name", "dir", "base", "ext", "file", "index"}
+
+// checkSheetName checks placeholders of --sheet-name.
+func checkSheetName(tmpl string) error {
+	for _, m := range sheetNamePlaceholderRE.FindAllStringSubmatch(tmpl, -1) {
+		found := false
+		for _, p := range sheetNamePlaceholders {
+			found = found || m[1] == p
+		}
+		if !found {
+			return fmt.Errorf("--sheet-name: unknown placeholder %v", m[0])
+		}
+	}
+	return nil
+}
+
+// expandSheetName expands --sheet-name for the CSV name.
+//
+//	data/2026/q1.csv: {name}=data/2026/q1.csv, {dir}=2026, {base}=q1, {ext}=.csv, {file}=q1.csv
+func expandSheetName(tmpl, name string, index int) string {
+	dir := filepath.Base(filepath.Dir(name))
+	if dir == "." || dir == string(filepath.Separator) {
+		dir = ""
+	}
+	file := filepath.Base(name)
+	ext := filepath.Ext(file)
+
+	return sheetNamePlaceholderRE.ReplaceAllStringFunc(tmpl, func(p string) string {
+		switch p {
+		case "{name}":
+			return name
+		case "{dir}":
+			return dir
+		case "{base}":
+			return strings.TrimSuffix(file, ext)
+		case "{ext}":
+			return ext
+		case "{file}":
+			return file
+		case "{index}":
+			return strconv.Itoa(index)
+		}
+		return p
+	})
+}
+
+// sanitizeSheetName replaces characters that Excel does not allow with _, and truncates the name to 31 characters.
+func sanitizeSheetName(name string) string {
+	name = invalidSheetNameCharRE.ReplaceAllString(name, "_")
+	// nor an apostrophe at either end
+	if strings.HasPrefix(name, "'") {
+		name = "_" + name[1:]
+	}
+	if strings.HasSuffix(name, "'") {
+		name = name[:len(name)-1] + "_"
+	}
+
+	if r := []rune(name); len(r) > excelize.MaxSheetNameLength {
+		name = string(r[:excelize.MaxSheetNameLength])
+	}
+
+	return name
+}
+
+// sheetNames names the sheets of the inputs by --sheet-name.
+// Names colliding case-insensitively are numbered as NAME_2, NAME_3... in the order of the inputs.
+func (c globalCmd) sheetNames(inputs []input) []string {
+	names := make([]string, 0, len(inputs))
+	used := make(map[string]bool)
+	for i, in := range inputs {
+		name := sanitizeSheetName(expandSheetName(c.SheetName, in.Name, i+1))
+		if strings.TrimSpace(name) == "" {
+			name = "Sheet" + strconv.Itoa(i+1)
+		}
+
+		unique := name
+		for n := 2; used[strings.ToLower(unique)]; n++ {
+			suffix := "_" + strconv.Itoa(n)
+			r := []rune(name)
+			if len(r)+len(suffix) > excelize.MaxSheetNameLength {
+				r = r[:excelize.MaxSheetNameLength-len(suffix)]
+			}
+			unique = string(r) + suffix
+		}
+		used[strings.ToLower(unique)] = true
+
+		names = append(names, unique)
+	}
+
+	return names
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// tableStyleRE matches built-in table styles of Excel.
+var tableStyleRE = regexp.MustCompile(`^(?i:TableStyle(?:Light(?:[1-9]|1\d|2[01])|Medium(?:[1-9]|1\d|2[0-8])|Dark(?:[1-9]|1[01])))$`)
+
+var (
+	invalidTableNameCharRE = regexp.MustCompile(`[^\p{L}\p{N}_.\\]`)
+	cellRefLikeRE          = regexp.MustCompile(`^(?i:[a-z]{1,3}\d+|r\d*c\d*|r|c)$`)
+)
+
+// maxTableNameLength is the limit of names of Excel.
+const maxTableNameLength = 255
+
+// tableName makes a valid and unique table name from the sheet name.
+// used holds lowercased names in the workbook.
+func tableName(sheet string, used map[string]bool) string {
+	name := invalidTableNameCharRE.ReplaceAllString(sheet, "_")
+	if name == "" {
+		name = "Table"
+	}
+	// a name begins with a letter, an underscore or a backslash, and is not like a cell reference
+	if r := []rune(name)[0]; !(unicode.IsLetter(r) || r == '_' || r == '\\') || cellRefLikeRE.MatchString(name) {
+		name = "_" + name
+	}
+	if r := []rune(name); len(r) > maxTableNameLength {
+		name = string(r[:maxTableNameLength])
+	}
+
+	unique := name
+	for n := 2; used[strings.ToLower(unique)]; n++ {
+		suffix := "_" + strconv.Itoa(n)
+		r := []rune(name)
+		if len(r)+len(suffix) > maxTableNameLength {
+			r = r[:maxTableNameLength-len(suffix)]
+		}
+		unique = string(r) + suffix
+	}
+	used[strings.ToLower(unique)] = true
+
+	return unique
+}
+
+// tableHeader makes column names of a table unique and not empty, as Excel requires.
+func tableHeader(fields []string) []string {
+	header := make([]string, len(fields))
+	seen := make(map[string]bool)
+	for i, name := range fields {
+		if strings.TrimSpace(name) == "" {
+			name = "Column" + strconv.Itoa(i+1)
+		}
+
+		unique := name
+		for n := 2; seen[strings.ToLower(unique)]; n++ {
+			unique = name + strconv.Itoa(n)
+		}
+		seen[strings.ToLower(unique)] = true
+
+		header[i] = unique
+	}
+
+	return header
+}
+
+// usedTableNames returns the lowercased names of tables in the workbook.
+// It reads all worksheets, so it must be called before any streaming.
+func usedTableNames(f *excelize.File) (map[string]bool, error) {
+	used := make(map[string]bool)
+	for _, sheet := range f.GetSheetList() {
+		tables, err := f.GetTables(sheet)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tables {
+			used[strings.ToLower(t.Name)] = true
+		}
+	}
+
+	return used, nil
+}
+
+// deleteTables deletes tables of the sheet,
+// which are left in the workbook by deleting the sheet.
+func deleteTables(f *excelize.File, sheet string) {
+	tables, err := f.GetTables(sheet)
+	if err != nil {
+		return
+	}
+	for _, t := range tables {
+		f.DeleteTable(t.Name)
+	}
+}
+
+// takeTable deletes the table at the origin of the existing sheet, to be added again over the rows written.
+// Its name and style are kept, as pivot tables and formulas refer to it, and so is its range, for clearSheet.
+func (c globalCmd) takeTable(oc outputContext, st *sheetState) error {
+	tables, err := oc.output.GetTables(st.xlsxsheet)
+	if err != nil {
+		return fmt.Errorf("%v: %v", st.xlsxsheet, err)
+	}
+
+	topLeft, err := excelize.CoordinatesToCellName(st.col0+1, st.row0+1)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tables {
+		if !strings.EqualFold(strings.Split(t.Range, ":")[0], topLeft) {
+			continue
+		}
+
+		err := oc.output.DeleteTable(t.Name)
+		if err != nil {
+			return fmt.Errorf("%v: %v", st.xlsxsheet, err)
+		}
+		st.table = &excelize.Table{Name: t.Name, Range: t.Range, StyleName: t.StyleName}
+		break
+	}
+
+	return nil
+}
+
+// addTable registers the header and the rows as a table.
+// Columns are those of the header, because Excel requires names of all columns.
+func (c globalCmd) addTable(oc outputContext, st *sheetState) error {
+	if len(st.header) == 0 {
+		return nil
+	}
+
+	rng, err := st.w.rangeName(st.rindex, len(st.header))
+	if err != nil {
+		return err
+	}
+
+	table := &excelize.Table{
+		Range:     rng,
+		StyleName: c.TableStyle,
+	}
+	if st.table != nil {
+		table.Name = st.table.Name
+		table.StyleName = st.table.StyleName
+		// not for continuation sheets
+		st.table = nil
+	} else {
+		table.Name = tableName(st.xlsxsheet, oc.tableNames)
+	}
+
+	err = st.w.addTable(table)
+	if err != nil {
+		return fmt.Errorf("%v: %v", st.xlsxsheet, err)
+	}
+
+	return nil
+}
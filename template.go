@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// intoRule is a declaration of --into, [CSV_NAME->]SHEET[!CELL].
+type intoRule struct {
+	Input string // a CSV name or a wildcard, empty for any
+	Sheet string
+	Cell  string // the origin, empty for A1
+}
+
+type intoRules []intoRule
+
+var intoCellRE = regexp.MustCompile(`^(.*)!([A-Za-z]{1,3}[1-9][0-9]*)$`)
+
+func parseIntoRules(decls []string) (intoRules, error) {
+	var rules intoRules
+	for _, decl := range decls {
+		var r intoRule
+
+		target := decl
+		if pos := strings.Index(decl, "->"); pos != -1 {
+			r.Input, target = strings.TrimSpace(decl[:pos]), decl[pos+2:]
+		}
+		if m := intoCellRE.FindStringSubmatch(target); m != nil {
+			target, r.Cell = m[1], strings.ToUpper(m[2])
+			if _, _, err := excelize.CellNameToCoordinates(r.Cell); err != nil {
+				return nil, fmt.Errorf("--into: %v", err)
+			}
+		}
+		r.Sheet = strings.TrimSpace(target)
+		if r.Sheet == "" {
+			return nil, fmt.Errorf("--into: no sheet in %q", decl)
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// find returns the rule of the CSV.
+// Names are matched with the whole name or the base name, and an exact name wins over patterns, and patterns win over rules without names.
+func (rr intoRules) find(name string) (intoRule, bool) {
+	names := []string{strings.ToLower(name), strings.ToLower(filepath.Base(name))}
+
+	for _, r := range rr {
+		for _, n := range names {
+			if r.Input != "" && strings.EqualFold(r.Input, n) {
+				return r, true
+			}
+		}
+	}
+	for _, r := range rr {
+		for _, n := range names {
+			if r.Input != "" && wildcardMatch(strings.ToLower(r.Input), n) {
+				return r, true
+			}
+		}
+	}
+	for _, r := range rr {
+		if r.Input == "" {
+			return r, true
+		}
+	}
+
+	return intoRule{}, false
+}
+
+// sheetTarget is where a CSV is written.
+type sheetTarget struct {
+	sheet string
+	cell  string // the origin, empty for A1
+}
+
+// targetOf returns where the CSV is written, by --into, or the sheet named by --sheet-name and --start-cell of it.
+func (c globalCmd) targetOf(oc outputContext, in input, sheet string) (sheetTarget, error) {
+	if r, found := oc.intos.find(in.Name); found && r.Cell != "" {
+		return sheetTarget{sheet: r.Sheet, cell: r.Cell}, nil
+	} else if found {
+		return c.sheetTargetOf(oc, r.Sheet)
+	}
+	return c.sheetTargetOf(oc, sheet)
+}
+
+func (c globalCmd) sheetTargetOf(oc outputContext, sheet string) (sheetTarget, error) {
+	cell, err := oc.layouts.startCell(sheet)
+	if err != nil {
+		return sheetTarget{}, err
+	}
+	return sheetTarget{sheet: sheet, cell: cell}, nil
+}
+
+// keepsSheet reports whether the existing sheet is written over, instead of being replaced by a new one.
+// Sheets of a template are kept for what refers to them, and so are sheets around the origin.
+func (c globalCmd) keepsSheet(t sheetTarget) bool {
+	return c.Append || c.Template != "" || t.cell != ""
+}
+
+// clearSheet clears the rows of a previous conversion (or placeholder rows) from the origin of the kept sheet,
+// so that none of them are left beneath the rows of a shorter CSV.
+// The rows are cleared down to the last one with values, as wide as the table taken by --table, or as the row at the origin.
+func (c globalCmd) clearSheet(oc outputContext, st *sheetState) error {
+	rows, err := oc.output.GetRows(st.xlsxsheet)
+	if err != nil {
+		return fmt.Errorf("%v: %v", st.xlsxsheet, err)
+	}
+
+	width, height := 0, len(rows)-st.row0
+	if height > 0 && len(rows[st.row0]) > st.col0 {
+		width = len(rows[st.row0]) - st.col0
+	}
+	if st.table != nil {
+		cells := strings.Split(st.table.Range, ":")
+		col, row, err := excelize.CellNameToCoordinates(cells[len(cells)-1])
+		if err != nil {
+			return fmt.Errorf("%v: %v", st.xlsxsheet, err)
+		}
+		width = col - st.col0
+		if row-st.row0 > height {
+			height = row - st.row0
+		}
+	}
+
+	for rindex := 0; rindex < height; rindex++ {
+		for cindex := 0; cindex < width; cindex++ {
+			addr, err := excelize.CoordinatesToCellName(st.col0+cindex+1, st.row0+rindex+1)
+			if err != nil {
+				return err
+			}
+			// styles of the template are kept
+			err = oc.output.SetCellValue(st.xlsxsheet, addr, nil)
+			if err != nil {
+				return fmt.Errorf("%v: %v", st.xlsxsheet, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkTemplate checks that --template is not overwritten by --output.
+func (c globalCmd) checkTemplate() error {
+	if c.Template == "" {
+		return nil
+	}
+
+	tmpl, err := os.Stat(c.Template)
+	if err != nil {
+		return fmt.Errorf("--template: %v", err)
+	}
+	if out, err := os.Stat(c.Output); err == nil && os.SameFile(tmpl, out) {
+		return errors.New("--template must be another file than --output")
+	}
+
+	return nil
+}
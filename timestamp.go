@@ -0,0 +1,83 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// epochUnits are input formats of date and datetime for Unix epochs.
+var epochUnits = map[string]time.Duration{
+	"epoch":    time.Second,
+	"epoch_ms": time.Millisecond,
+	"epoch_us": time.Microsecond,
+}
+
+const excelSerialLayout = "excel_serial"
+
+var epochRE = regexp.MustCompile(`^([+-]?\d+)(?:\.(\d+))?$`)
+
+// parseTimestamp parses a Unix epoch (epoch, epoch_ms or epoch_us) or a serial number of Excel (excel_serial).
+// An epoch is converted into the location, and a serial number is a wall clock in the location (UTC for nil).
+func parseTimestamp(value string, loc *time.Location, layouts ...string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	for _, layout := range layouts {
+		layout = strings.ToLower(layout)
+
+		if layout == excelSerialLayout {
+			serial, err := strconv.ParseFloat(value, 64)
+			if err != nil || !epochRE.MatchString(value) {
+				continue
+			}
+			t, err := excelize.ExcelDateToTime(serial, false)
+			if err != nil {
+				continue
+			}
+			// to the nearest millisecond, against errors of floats
+			t = t.Round(time.Millisecond)
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc), true
+		}
+
+		unit, found := epochUnits[layout]
+		if !found {
+			continue
+		}
+		m := epochRE.FindStringSubmatch(value)
+		if m == nil {
+			continue
+		}
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var frac time.Duration
+		if m[2] != "" {
+			f, _ := strconv.ParseFloat("0."+m[2], 64)
+			frac = time.Duration(f * float64(unit))
+			if strings.HasPrefix(m[1], "-") {
+				frac = -frac
+			}
+		}
+
+		var t time.Time
+		switch unit {
+		case time.Second:
+			t = time.Unix(n, 0)
+		case time.Millisecond:
+			t = time.UnixMilli(n)
+		default:
+			t = time.UnixMicro(n)
+		}
+		return t.Add(frac).In(loc), true
+	}
+
+	return time.Time{}, false
+}
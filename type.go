@@ -15,6 +15,9 @@ const (
 	typeDate     baseType = "date"
 	typeTime     baseType = "time"
 	typeDatetime baseType = "datetime"
+	typeDuration baseType = "duration"
+	typePercent  baseType = "percent"
+	typeCurrency baseType = "currency"
 	typeBool     baseType = "bool"
 	typeFormula  baseType = "formula"
 )
@@ -40,6 +43,9 @@ func (t baseType) derive(explicitInputFormat, explicitOutputFormat string) deriv
 	return derived
 }
 
+// typeNamesPattern is an alternation of the types to declare, longer names first.
+const typeNamesPattern = `text|number|percent|currency|datetime|date|time|duration|bool|formula`
+
 type derivedType struct {
 	baseType baseType
 
@@ -50,6 +56,14 @@ type derivedType struct {
 	implicitOutputFormat string
 }
 
+// withImplicitOutputFormat returns the type displayed by the format, unless an output format is given.
+func (t derivedType) withImplicitOutputFormat(format string) derivedType {
+	if t.explicitOutputFormat == "" && t.implicitOutputFormat == "" {
+		t.implicitOutputFormat = format
+	}
+	return t
+}
+
 func (t derivedType) String() string {
 	s := string(t.baseType)
 	if t.explicitInputFormat != "" || t.implicitInputFormat != "" || t.explicitOutputFormat != "" || t.implicitOutputFormat != "" {
@@ -78,11 +92,34 @@ func (t derivedType) String() string {
 	return s
 }
 
+// spec returns the declaration in the syntax of --columns, with the formats in effect.
+func (t derivedType) spec() string {
+	in := t.explicitInputFormat
+	if in == "" {
+		in = t.implicitInputFormat
+	}
+	out := t.explicitOutputFormat
+	if out == "" {
+		out = t.implicitOutputFormat
+	}
+
+	s := string(t.baseType)
+	if in != "" || out != "" {
+		s += "(" + in
+		if out != "" {
+			s += "->" + out
+		}
+		s += ")"
+	}
+
+	return s
+}
+
 var implicitInputFormats map[baseType]string
 var implicitOutputFormats map[baseType]string
 
 func parseType(s string) (derivedType, error) {
-	declRE := regexp.MustCompile(`(text|number|datetime|date|time|bool|formula)(?:\((.*?)(?:->(.+))?\))?`)
+	declRE := regexp.MustCompile(`(` + typeNamesPattern + `)(?:\((.*?)(?:->(.+))?\))?`)
 	subs := declRE.FindStringSubmatch(s)
 	if subs == nil {
 		return derivedType{}, fmt.Errorf("invalid type declaration %q", s)
@@ -94,7 +131,7 @@ func parseType(s string) (derivedType, error) {
 	return derived, nil
 }
 
-func initImplicitDecls(dil, dol, til, tol, dtil, dtol, nol string) {
+func initImplicitDecls(dil, dol, til, tol, dtil, dtol, durol, nol string) {
 	implicitInputFormats = make(map[baseType]string)
 	implicitInputFormats[typeDate] = dil
 	implicitInputFormats[typeTime] = til
@@ -104,5 +141,7 @@ func initImplicitDecls(dil, dol, til, tol, dtil, dtol, nol string) {
 	implicitOutputFormats[typeDate] = dol
 	implicitOutputFormats[typeTime] = tol
 	implicitOutputFormats[typeDatetime] = dtol
+	implicitOutputFormats[typeDuration] = durol
 	implicitOutputFormats[typeNumber] = nol
+	implicitOutputFormats[typePercent] = "0.00%"
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// for --tz without the zoneinfo of the system
+	_ "time/tzdata"
+)
+
+var (
+	tzRE     = regexp.MustCompile(`(?i)(?:^|\s+)tz[=:](\S+)$`)
+	offsetRE = regexp.MustCompile(`^([+-])(\d{2}):?(\d{2})$`)
+)
+
+var zoneCache = make(map[string]*time.Location)
+
+// lookupZone returns the location of an IANA name (Asia/Tokyo), UTC, Local or an offset (+09:00).
+func lookupZone(name string) (*time.Location, error) {
+	if loc, found := zoneCache[name]; found {
+		return loc, nil
+	}
+
+	var loc *time.Location
+	if m := offsetRE.FindStringSubmatch(name); m != nil {
+		h, _ := strconv.Atoi(m[2])
+		min, _ := strconv.Atoi(m[3])
+		offset := h*3600 + min*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		loc = time.FixedZone(name, offset)
+	} else {
+		var err error
+		loc, err = time.LoadLocation(name)
+		if err != nil || name == "" {
+			return nil, fmt.Errorf("unknown time zone %q", name)
+		}
+	}
+	zoneCache[name] = loc
+
+	return loc, nil
+}
+
+// splitZone splits tz=ZONE (or tz:ZONE) off the end of an input format.
+func splitZone(format string) (string, string) {
+	m := tzRE.FindStringSubmatchIndex(format)
+	if m == nil {
+		return format, ""
+	}
+	return format[:m[0]], format[m[2]:m[3]]
+}
+
+// zonesOf returns the input format of the type without tz=ZONE,
+// the location of values without time zones (the zone of the type, or --tz),
+// and the location to display values in (--tz, or the zone of the type).
+// Locations are nil when neither is given, for values as written.
+func (c globalCmd) zonesOf(t derivedType) (string, *time.Location, *time.Location) {
+	format, zone := splitZone(t.explicitInputFormat)
+
+	// checked by Before and parseHints
+	var in, out *time.Location
+	if c.TZ != "" {
+		out, _ = lookupZone(c.TZ)
+	}
+	if zone != "" {
+		in, _ = lookupZone(zone)
+	}
+
+	if in == nil {
+		in = out
+	}
+	if out == nil {
+		out = in
+	}
+	return format, in, out
+}
+
+// inZone converts the time into the location, if any.
+func inZone(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// hasZone reports whether the layout of time.Parse has a time zone, whose values vary in length (Z or +09:00).
+func hasZone(layout string) bool {
+	return strings.Contains(layout, "Z07") || strings.Contains(layout, "-07") || strings.Contains(layout, "MST")
+}
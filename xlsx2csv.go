@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/transform"
+)
+
+type xlsx2csvCmd struct {
+	Sheet    []string `cli:"sheet,s=SHEET" help:"sheets to export, by names or wildcards (default: all)"`
+	Dir      string   `cli:"dir=DIR" default:"." help:"the directory of CSV files, named after sheets"`
+	Raw      bool     `cli:"raw" help:"export values as stored, instead of as displayed by their formats"`
+	Date     string   `cli:"date=FORMAT" help:"render dates by FORMAT (yyyy/mm/dd as --date-xlsx), instead of their cell formats"`
+	Time     string   `cli:"time=FORMAT" help:"render times by FORMAT (hh:mm:ss as --time-xlsx), instead of their cell formats"`
+	Datetime string   `cli:"datetime=FORMAT" help:"render datetimes by FORMAT (yyyy/mm/dd hh:mm:ss as --datetime-xlsx), instead of their cell formats"`
+}
+
+func (x xlsx2csvCmd) Before(args []string) error {
+	if len(args) != 1 {
+		return errors.New("an xlsx file is required")
+	}
+
+	return nil
+}
+
+func (x xlsx2csvCmd) Run(g *globalCmd, args []string) error {
+	f, err := excelize.OpenFile(filepath.Clean(args[0]))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sheets := x.sheets(f)
+	if len(sheets) == 0 {
+		return fmt.Errorf("%v: no sheets to export", args[0])
+	}
+
+	enc, err := lookupEncoding(g.Encoding)
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]bool)
+	for _, sheet := range sheets {
+		name := filepath.Join(x.Dir, csvFileName(sheet, used))
+
+		file, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = file
+		var tw *transform.Writer
+		if enc != nil {
+			tw = transform.NewWriter(file, enc.NewEncoder())
+			w = tw
+		}
+
+		err = x.export(w, *g, f, sheet)
+		if err == nil && tw != nil {
+			err = tw.Close()
+		}
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// sheets returns the sheets matching --sheet, in the order of the workbook.
+func (x xlsx2csvCmd) sheets(f *excelize.File) []string {
+	var sheets []string
+	for _, sheet := range f.GetSheetList() {
+		if len(x.Sheet) == 0 {
+			sheets = append(sheets, sheet)
+			continue
+		}
+
+		for _, ptn := range x.Sheet {
+			if strings.EqualFold(ptn, sheet) || wildcardMatch(strings.ToLower(ptn), strings.ToLower(sheet)) {
+				sheets = append(sheets, sheet)
+				break
+			}
+		}
+	}
+
+	return sheets
+}
+
+// export writes the sheet as CSV. Rows are as wide as the widest one.
+func (x xlsx2csvCmd) export(w io.Writer, c globalCmd, f *excelize.File, sheet string) error {
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: x.Raw})
+	if err != nil {
+		return err
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	var dates *dateRenderer
+	if x.Date != "" || x.Time != "" || x.Datetime != "" {
+		dates, err = newDateRenderer(f)
+		if err != nil {
+			return err
+		}
+		defer dates.close()
+	}
+
+	cw := csv.NewWriter(w)
+	if !strings.EqualFold(c.Delimiter, "auto") {
+		cw.Comma = parseDelimiter(c.Delimiter)
+	}
+
+	for rindex, row := range rows {
+		record := make([]string, width)
+		copy(record, row)
+
+		if dates != nil {
+			for cindex := range row {
+				record[cindex], err = x.renderDate(dates, f, sheet, cindex, rindex, record[cindex])
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err := cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// renderDate renders the value of a date, a time or a datetime cell by --date, --time or --datetime.
+func (x xlsx2csvCmd) renderDate(dates *dateRenderer, f *excelize.File, sheet string, cindex, rindex int, value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	addr, err := excelize.CoordinatesToCellName(cindex+1, rindex+1)
+	if err != nil {
+		return "", err
+	}
+	styleID, err := f.GetCellStyle(sheet, addr)
+	if err != nil {
+		return "", err
+	}
+
+	var format string
+	switch dates.kindOf(styleID) {
+	case typeDate:
+		format = x.Date
+	case typeTime:
+		format = x.Time
+	case typeDatetime:
+		format = x.Datetime
+	}
+	if format == "" {
+		return value, nil
+	}
+
+	raw, err := f.GetCellValue(sheet, addr, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return "", err
+	}
+	serial, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		// text in a date cell
+		return value, nil
+	}
+
+	return dates.render(serial, format)
+}
+
+// dateRenderer renders serial values by number formats of Excel, through a scratch workbook.
+type dateRenderer struct {
+	f       *excelize.File
+	scratch *excelize.File
+
+	styles map[string]int
+	kinds  map[int]baseType
+}
+
+func newDateRenderer(f *excelize.File) (*dateRenderer, error) {
+	scratch := excelize.NewFile()
+
+	props, err := f.GetWorkbookProps()
+	if err != nil {
+		return nil, err
+	}
+	err = scratch.SetWorkbookProps(&excelize.WorkbookPropsOptions{Date1904: props.Date1904})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dateRenderer{
+		f:       f,
+		scratch: scratch,
+		styles:  make(map[string]int),
+		kinds:   make(map[int]baseType),
+	}, nil
+}
+
+func (r *dateRenderer) close() {
+	r.scratch.Close()
+}
+
+func (r *dateRenderer) render(serial float64, format string) (string, error) {
+	style, found := r.styles[format]
+	if !found {
+		var err error
+		style, err = defineStyle(r.scratch, format)
+		if err != nil {
+			return "", err
+		}
+		r.styles[format] = style
+	}
+
+	err := setCellValueAndStyle(r.scratch, "Sheet1", "A1", serial, style)
+	if err != nil {
+		return "", err
+	}
+
+	return r.scratch.GetCellValue("Sheet1", "A1")
+}
+
+// kindOf returns typeDate, typeTime or typeDatetime by the number format of the style, otherwise typeUnknown.
+func (r *dateRenderer) kindOf(styleID int) baseType {
+	kind, found := r.kinds[styleID]
+	if found {
+		return kind
+	}
+
+	kind = typeUnknown
+	if style, err := r.f.GetStyle(styleID); err == nil {
+		if style.CustomNumFmt != nil {
+			kind = numFmtKind(*style.CustomNumFmt)
+		} else {
+			kind = builtInNumFmtKinds[style.NumFmt]
+		}
+	}
+	r.kinds[styleID] = kind
+
+	return kind
+}
+
+// builtInNumFmtKinds are built-in number formats of dates and times (including those of East Asian languages).
+var builtInNumFmtKinds = map[int]baseType{
+	14: typeDate, 15: typeDate, 16: typeDate, 17: typeDate,
+	18: typeTime, 19: typeTime, 20: typeTime, 21: typeTime,
+	22: typeDatetime,
+	27: typeDate, 28: typeDate, 29: typeDate, 30: typeDate, 31: typeDate,
+	32: typeTime, 33: typeTime, 34: typeTime, 35: typeTime,
+	36: typeDate,
+	45: typeTime, 46: typeTime, 47: typeTime,
+	50: typeDate, 51: typeDate, 52: typeDate, 53: typeDate, 54: typeDate,
+	55: typeTime, 56: typeTime,
+	57: typeDate, 58: typeDate,
+}
+
+var (
+	numFmtElapsedRE = regexp.MustCompile(`\[(?i:h+|m+|s+)\]`)
+	numFmtLiteralRE = regexp.MustCompile(`"[^"]*"|\\.|\[[^\]]*\]|_.|\*.`)
+)
+
+// numFmtKind classifies the number format by its first section.
+// Elapsed times like [h]:mm:ss are durations, not times.
+func numFmtKind(code string) baseType {
+	code, _, _ = strings.Cut(code, ";")
+	if numFmtElapsedRE.MatchString(code) {
+		return typeUnknown
+	}
+	code = strings.ToLower(numFmtLiteralRE.ReplaceAllString(code, ""))
+
+	date := strings.ContainsAny(code, "yd")
+	time := strings.ContainsAny(code, "hs")
+	switch {
+	case date && time:
+		return typeDatetime
+	case date:
+		return typeDate
+	case time:
+		return typeTime
+	}
+	return typeUnknown
+}
+
+var invalidFileNameCharRE = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)
+
+// csvFileName makes a file name of the sheet, unique in used (lowercased names).
+// A sheet named after a CSV, like data.csv, is exported as it is.
+func csvFileName(sheet string, used map[string]bool) string {
+	base := strings.TrimSpace(invalidFileNameCharRE.ReplaceAllString(sheet, "_"))
+	if strings.EqualFold(filepath.Ext(base), ".csv") {
+		base = base[:len(base)-len(".csv")]
+	}
+	if base == "" {
+		base = "_"
+	}
+
+	name := base + ".csv"
+	for n := 2; used[strings.ToLower(name)]; n++ {
+		name = base + "_" + strconv.Itoa(n) + ".csv"
+	}
+	used[strings.ToLower(name)] = true
+
+	return name
+}